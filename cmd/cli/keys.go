@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"ner-service-go/internal/auth"
+)
+
+var (
+	keysFile  string
+	keyName   string
+	keyLimits auth.Limits
+	keysUsage string
+)
+
+func newKeysCmd() *cobra.Command {
+	keysCmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage API keys",
+		Long:  "Create, revoke and list the API keys accepted by the server. The server picks up changes to the keys file without a restart.",
+	}
+
+	defaultFile := os.Getenv("NER_API_KEYS_FILE")
+	if defaultFile == "" {
+		defaultFile = "keys.json"
+	}
+	keysCmd.PersistentFlags().StringVar(&keysFile, "keys-file", defaultFile, "API keys file, NER_API_KEYS_FILE when set")
+
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an API key",
+		Long:  "Create an API key and print it. Only a hash of the key is stored, so it cannot be shown again.",
+		Args:  cobra.NoArgs,
+		Run:   runKeysCreate,
+	}
+	createCmd.Flags().StringVarP(&keyName, "name", "n", "", "Name of the client the key is for (required)")
+	createCmd.Flags().Float64Var(&keyLimits.RatePerSecond, "rate", 0, "Requests per second, 0 for unlimited")
+	createCmd.Flags().IntVar(&keyLimits.Burst, "burst", 1, "Requests allowed at once above the rate")
+	createCmd.Flags().Int64Var(&keyLimits.DailyChars, "daily-chars", 0, "Characters per UTC day, 0 for unlimited")
+	createCmd.Flags().Int64Var(&keyLimits.MonthlyChars, "monthly-chars", 0, "Characters per UTC month, 0 for unlimited")
+	createCmd.MarkFlagRequired("name")
+
+	revokeCmd := &cobra.Command{
+		Use:   "revoke <key-id>",
+		Short: "Revoke an API key",
+		Args:  cobra.ExactArgs(1),
+		Run:   runKeysRevoke,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List API keys",
+		Args:  cobra.NoArgs,
+		Run:   runKeysList,
+	}
+	defaultUsage := os.Getenv("NER_API_USAGE_FILE")
+	if defaultUsage == "" {
+		defaultUsage = "usage.json"
+	}
+	listCmd.Flags().StringVar(&keysUsage, "usage-file", defaultUsage, "Usage counters file written by the server, NER_API_USAGE_FILE when set")
+
+	keysCmd.AddCommand(createCmd, revokeCmd, listCmd)
+	return keysCmd
+}
+
+func runKeysCreate(cmd *cobra.Command, args []string) {
+	file, err := auth.LoadKeyFile(keysFile)
+	if err != nil {
+		log.Fatalf("Error loading keys: %v", err)
+	}
+	key, plaintext, err := file.Create(keyName, keyLimits)
+	if err != nil {
+		log.Fatalf("Error creating key: %v", err)
+	}
+	if err := file.Save(keysFile); err != nil {
+		log.Fatalf("Error saving keys: %v", err)
+	}
+
+	fmt.Printf("Created key %s for %s:\n\n  %s\n\nStore it now, it cannot be shown again.\n", key.ID, key.Name, plaintext)
+}
+
+func runKeysRevoke(cmd *cobra.Command, args []string) {
+	file, err := auth.LoadKeyFile(keysFile)
+	if err != nil {
+		log.Fatalf("Error loading keys: %v", err)
+	}
+	if err := file.Revoke(args[0]); err != nil {
+		log.Fatalf("Error revoking key %s: %v", args[0], err)
+	}
+	if err := file.Save(keysFile); err != nil {
+		log.Fatalf("Error saving keys: %v", err)
+	}
+	fmt.Printf("Revoked key %s\n", args[0])
+}
+
+func runKeysList(cmd *cobra.Command, args []string) {
+	file, err := auth.LoadKeyFile(keysFile)
+	if err != nil {
+		log.Fatalf("Error loading keys: %v", err)
+	}
+	usage, err := auth.OpenUsage(keysUsage)
+	if err != nil {
+		log.Fatalf("Error loading usage: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCREATED\tSTATUS\tRATE\tTODAY\tTHIS MONTH")
+	now := time.Now()
+	for _, key := range file.Keys {
+		status := "active"
+		if key.Revoked != nil {
+			status = "revoked " + key.Revoked.Format("2006-01-02")
+		}
+		rate := "unlimited"
+		if key.RatePerSecond > 0 {
+			rate = fmt.Sprintf("%g/s burst %d", key.RatePerSecond, max(key.Burst, 1))
+		}
+		day, month := usage.Used("key:"+key.ID, now)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Created.Format("2006-01-02"), status, rate,
+			formatQuota(day, key.DailyChars), formatQuota(month, key.MonthlyChars))
+	}
+	w.Flush()
+}
+
+// formatQuota shows characters used out of a quota.
+func formatQuota(used, limit int64) string {
+	if limit <= 0 {
+		return strconv.FormatInt(used, 10)
+	}
+	return fmt.Sprintf("%d/%d", used, limit)
+}
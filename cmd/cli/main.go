@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 
 	"github.com/spf13/cobra"
+	"ner-service-go/internal/charset"
 	"ner-service-go/internal/config"
+	"ner-service-go/internal/docextract"
 	"ner-service-go/internal/ner"
 	"ner-service-go/internal/version"
 )
@@ -17,6 +21,10 @@ var (
 	modelPath string
 	inputFile string
 	outputJSON bool
+	backend   string
+	charsetName string
+	truecaseModel string
+	socialMode    bool
 )
 
 func main() {
@@ -27,9 +35,13 @@ func main() {
 		Run:   runNER,
 	}
 
-	rootCmd.Flags().StringVarP(&modelPath, "model", "m", "", "Path to MITIE model file (default: models/ner_model.dat)")
+	rootCmd.Flags().StringVarP(&modelPath, "model", "m", "", "Path to the model file for the selected backend (default: models/ner_model.dat)")
 	rootCmd.Flags().StringVarP(&inputFile, "file", "f", "", "Input file path (if not provided, reads from stdin)")
 	rootCmd.Flags().BoolVarP(&outputJSON, "json", "j", false, "Output in JSON format")
+	rootCmd.Flags().StringVarP(&backend, "backend", "b", "", "NER backend: mitie or perceptron (default: mitie)")
+	rootCmd.Flags().BoolVarP(&socialMode, "social", "s", false, "Social media mode: segment hashtags, resolve @handles and report HASHTAG, MENTION and EMOJI spans")
+	rootCmd.Flags().StringVar(&truecaseModel, "truecase-model", "", "Truecasing model for all-capitals and all-lowercase input (default: disabled)")
+	rootCmd.Flags().StringVar(&charsetName, "charset", "", "Input encoding, e.g. utf-8, iso-8859-1 or windows-1252 (default: detected)")
 
 	// Add version command
 	var versionCmd = &cobra.Command{
@@ -41,6 +53,10 @@ func main() {
 		},
 	}
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(newTrainCmd())
+	rootCmd.AddCommand(newTrainTruecaseCmd())
+	rootCmd.AddCommand(newStreamCmd())
+	rootCmd.AddCommand(newKeysCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal(err)
@@ -49,35 +65,61 @@ func main() {
 
 func runNER(cmd *cobra.Command, args []string) {
 	cfg := config.Load()
+	if backend != "" {
+		cfg.Backend = backend
+	}
+	opts := ner.OptionsFromConfig(cfg)
 	if modelPath != "" {
-		cfg.ModelPath = modelPath
+		opts.ModelPath = modelPath
+	}
+	if truecaseModel != "" {
+		opts.TruecaseModelPath = truecaseModel
 	}
 
-	nerService, err := ner.NewService(cfg.ModelPath)
+	nerService, err := ner.NewService(opts)
 	if err != nil {
 		log.Fatalf("Failed to initialize NER service: %v", err)
 	}
 	defer nerService.Close()
 
-	var text string
+	var entities []ner.Entity
 	if inputFile != "" {
 		data, err := ioutil.ReadFile(inputFile)
 		if err != nil {
 			log.Fatalf("Error reading file: %v", err)
 		}
-		text = string(data)
+		// DOCX, ODT, PDF, RTF, EPUB and HTML files are detected and their
+		// text extracted; anything else is read as plain text
+		doc, err := docextract.Extract(inputFile, data, charsetName)
+		if err != nil {
+			log.Fatalf("Error reading document: %v%s", err, charsetHint(err))
+		}
+		if socialMode {
+			entities, err = nerService.ExtractSocial(context.Background(), doc.Text)
+		} else {
+			entities, err = nerService.ExtractDocument(context.Background(), doc)
+		}
+		if err != nil {
+			log.Fatalf("Error extracting entities: %v", err)
+		}
 	} else if len(args) > 0 {
-		text = args[0]
+		text, _, err := charset.DecodeString(args[0], charsetName)
+		if err != nil {
+			log.Fatalf("Error reading text: %v%s", err, charsetHint(err))
+		}
+		if socialMode {
+			entities, err = nerService.ExtractSocial(context.Background(), text)
+		} else {
+			entities, err = nerService.ExtractEntities(context.Background(), text)
+		}
+		if err != nil {
+			log.Fatalf("Error extracting entities: %v", err)
+		}
 	} else {
 		fmt.Println("Please provide text as argument or use --file flag")
 		os.Exit(1)
 	}
 
-	entities, err := nerService.ExtractEntities(text)
-	if err != nil {
-		log.Fatalf("Error extracting entities: %v", err)
-	}
-
 	if outputJSON {
 		jsonOutput, err := json.MarshalIndent(entities, "", "  ")
 		if err != nil {
@@ -87,7 +129,27 @@ func runNER(cmd *cobra.Command, args []string) {
 	} else {
 		fmt.Printf("Found %d entities:\n\n", len(entities))
 		for i, entity := range entities {
-			fmt.Printf("%d. %s (%s) - Score: %s\n", i+1, entity.Label, entity.Tag, entity.Score)
+			fmt.Printf("%d. %s (%s) - Score: %s%s\n", i+1, entity.Label, entity.Tag, entity.Score, formatLocation(entity))
 		}
 	}
-}
\ No newline at end of file
+}
+
+// formatLocation describes the page or paragraph an entity was found in.
+func formatLocation(entity ner.Entity) string {
+	switch {
+	case entity.Page > 0:
+		return fmt.Sprintf(" - Page %d", entity.Page)
+	case entity.Paragraph > 0:
+		return fmt.Sprintf(" - Paragraph %d", entity.Paragraph)
+	default:
+		return ""
+	}
+}
+
+// charsetHint points at --charset when the input encoding was not detected.
+func charsetHint(err error) string {
+	if charsetName == "" && errors.Is(err, charset.ErrInvalidUTF8) {
+		return " (use --charset to set the input encoding)"
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	streamServer string
+	streamFile   string
+	streamOrder  string
+)
+
+func newStreamCmd() *cobra.Command {
+	streamCmd := &cobra.Command{
+		Use:   "stream",
+		Short: "Stream JSONL documents through a running server",
+		Long:  "Send a JSONL file of {\"id\", \"text\", \"options\"} documents to a server's /ner/stream endpoint and print one JSON result per line as they arrive",
+		Run:   runStream,
+	}
+
+	streamCmd.Flags().StringVar(&streamServer, "server", "http://localhost:8080", "Base URL of the NER server")
+	streamCmd.Flags().StringVarP(&streamFile, "file", "f", "", "JSONL input file (default: standard input)")
+	streamCmd.Flags().StringVar(&streamOrder, "order", "input", "Result order: input or completed")
+
+	return streamCmd
+}
+
+func runStream(cmd *cobra.Command, args []string) {
+	var input io.Reader = os.Stdin
+	if streamFile != "" {
+		f, err := os.Open(streamFile)
+		if err != nil {
+			log.Fatalf("Error opening input: %v", err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	url := strings.TrimSuffix(streamServer, "/") + "/ner/stream?order=" + streamOrder
+	req, err := http.NewRequest(http.MethodPost, url, input)
+	if err != nil {
+		log.Fatalf("Error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatalf("Error contacting server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Fatalf("Server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		fmt.Fprintln(os.Stderr)
+		log.Fatalf("Error reading results: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"ner-service-go/internal/perceptron"
+)
+
+var (
+	trainData       string
+	trainOutput     string
+	trainGazetteer  string
+	trainIterations int
+	trainSeed       int64
+)
+
+func newTrainCmd() *cobra.Command {
+	trainCmd := &cobra.Command{
+		Use:   "train",
+		Short: "Train a pure-Go perceptron model",
+		Long:  "Train an averaged perceptron model for the perceptron backend from CoNLL-formatted data (token and BIO tag per line, blank line between sentences)",
+		Run:   runTrain,
+	}
+
+	trainCmd.Flags().StringVarP(&trainData, "data", "d", "", "Training data in CoNLL format (required)")
+	trainCmd.Flags().StringVarP(&trainOutput, "output", "o", "models/perceptron.model", "Output model file")
+	trainCmd.Flags().StringVarP(&trainGazetteer, "gazetteer", "g", "", "Optional gazetteer file with TAG<TAB>name lines")
+	trainCmd.Flags().IntVarP(&trainIterations, "iterations", "i", 10, "Number of training passes")
+	trainCmd.Flags().Int64Var(&trainSeed, "seed", 1, "Random seed for shuffling the training data")
+	trainCmd.MarkFlagRequired("data")
+
+	return trainCmd
+}
+
+func runTrain(cmd *cobra.Command, args []string) {
+	data, err := os.Open(trainData)
+	if err != nil {
+		log.Fatalf("Error opening training data: %v", err)
+	}
+	sentences, err := perceptron.ReadCoNLL(data)
+	data.Close()
+	if err != nil {
+		log.Fatalf("Error reading training data: %v", err)
+	}
+
+	var gazetteer *perceptron.Gazetteer
+	if trainGazetteer != "" {
+		f, err := os.Open(trainGazetteer)
+		if err != nil {
+			log.Fatalf("Error opening gazetteer: %v", err)
+		}
+		gazetteer, err = perceptron.ReadGazetteer(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("Error reading gazetteer: %v", err)
+		}
+	}
+
+	fmt.Printf("Training on %d sentences...\n", len(sentences))
+	model, err := perceptron.Train(sentences, perceptron.TrainOptions{
+		Iterations: trainIterations,
+		Seed:       trainSeed,
+		Gazetteer:  gazetteer,
+		Progress: func(iteration int, accuracy float64) {
+			fmt.Printf("Iteration %d: token accuracy %.4f\n", iteration, accuracy)
+		},
+	})
+	if err != nil {
+		log.Fatalf("Error training model: %v", err)
+	}
+
+	if err := model.SaveFile(trainOutput); err != nil {
+		log.Fatalf("Error saving model: %v", err)
+	}
+	fmt.Printf("Model saved to %s (%d features)\n", trainOutput, len(model.Weights))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"ner-service-go/internal/truecase"
+)
+
+var (
+	truecaseCorpus string
+	truecaseOutput string
+)
+
+func newTrainTruecaseCmd() *cobra.Command {
+	trainTruecaseCmd := &cobra.Command{
+		Use:   "train-truecase",
+		Short: "Train a truecasing model",
+		Long:  "Train a frequency-based truecasing model from a plain-text corpus of well-cased Spanish text, used to restore capitalization of all-capitals and all-lowercase input",
+		Run:   runTrainTruecase,
+	}
+
+	trainTruecaseCmd.Flags().StringVarP(&truecaseCorpus, "corpus", "c", "", "Plain-text corpus, one paragraph or sentence per line (required)")
+	trainTruecaseCmd.Flags().StringVarP(&truecaseOutput, "output", "o", "models/truecase.model", "Output model file")
+	trainTruecaseCmd.MarkFlagRequired("corpus")
+
+	return trainTruecaseCmd
+}
+
+func runTrainTruecase(cmd *cobra.Command, args []string) {
+	corpus, err := os.Open(truecaseCorpus)
+	if err != nil {
+		log.Fatalf("Error opening corpus: %v", err)
+	}
+	model, err := truecase.Train(corpus)
+	corpus.Close()
+	if err != nil {
+		log.Fatalf("Error training model: %v", err)
+	}
+
+	if err := model.SaveFile(truecaseOutput); err != nil {
+		log.Fatalf("Error saving model: %v", err)
+	}
+	fmt.Printf("Model saved to %s (%d words)\n", truecaseOutput, len(model.Forms))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/auth"
+	"ner-service-go/internal/config"
+	"ner-service-go/internal/tlsconfig"
+)
+
+// usageFlushInterval is how often usage counters are saved.
+const usageFlushInterval = 10 * time.Second
+
+// requireAuth authenticates requests with an API key or a JWT, sent as a
+// bearer token, or an API key in the X-API-Key header, or else with a
+// verified TLS client certificate, and applies the client's rate limit,
+// reported in RateLimit-* headers. Clients whose token does not allow the
+// model are refused.
+func requireAuth(authenticator *auth.Authenticator, model string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := requestToken(c)
+		subject := tlsconfig.ClientSubject(c.Request.TLS)
+		if token == "" && subject == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key or bearer token required", "code": "unauthorized"})
+			return
+		}
+
+		var principal *auth.Principal
+		var err error
+		if token == "" {
+			principal = authenticator.AuthenticateCert(subject)
+		} else {
+			principal, err = authenticator.Authenticate(token)
+		}
+		if errors.Is(err, auth.ErrNoScope) {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token has no scope granting access", "code": "forbidden"})
+			return
+		}
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			message := "Invalid API key"
+			switch {
+			case errors.Is(err, auth.ErrRevokedKey):
+				message = "API key has been revoked"
+			case errors.Is(err, auth.ErrInvalidToken):
+				message = "Invalid token"
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message, "code": "unauthorized"})
+			return
+		}
+		principal.CertSubject = subject
+		if !principal.AllowsModel(model) {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token does not allow the " + model + " model", "code": "forbidden"})
+			return
+		}
+
+		limit := authenticator.Allow(principal)
+		if limit.Limit > 0 {
+			c.Header("RateLimit-Limit", strconv.Itoa(limit.Limit))
+			c.Header("RateLimit-Remaining", strconv.Itoa(limit.Remaining))
+			c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(limit.Reset)))
+		}
+		if !limit.Allowed {
+			c.Header("Retry-After", strconv.Itoa(max(ceilSeconds(limit.RetryAfter), 1)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded", "code": "rate_limited"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// authOptions returns the authentication settings of cfg: API keys when a
+// keys file is set, and JWTs when a JWKS is.
+func authOptions(cfg *config.Config) (auth.Options, error) {
+	opts := auth.Options{KeysFile: cfg.APIKeysFile, UsageFile: cfg.APIUsageFile}
+	if cfg.JWTJWKS == "" {
+		return opts, nil
+	}
+	var scopes map[string]auth.Scope
+	if cfg.JWTScopesFile != "" {
+		file, err := auth.LoadScopeFile(cfg.JWTScopesFile)
+		if err != nil {
+			return opts, err
+		}
+		scopes = file.Scopes
+	}
+	opts.JWT = &auth.JWTOptions{
+		JWKS:        cfg.JWTJWKS,
+		JWKSRefresh: cfg.JWTJWKSRefresh,
+		Issuer:      cfg.JWTIssuer,
+		Audience:    cfg.JWTAudience,
+		Scopes:      scopes,
+	}
+	return opts, nil
+}
+
+// requestToken returns the bearer token or X-API-Key header of a request.
+func requestToken(c *gin.Context) string {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(c.GetHeader("X-API-Key"))
+}
+
+// chargeQuota counts the characters of text against the character quotas
+// of the authenticated client, if any.
+func chargeQuota(ctx context.Context, text string) error {
+	if principal := auth.PrincipalFrom(ctx); principal != nil {
+		return principal.Charge(utf8.RuneCountInString(text))
+	}
+	return nil
+}
+
+// flushUsage saves usage counters periodically.
+func flushUsage(authenticator *auth.Authenticator) {
+	for range time.Tick(usageFlushInterval) {
+		if err := authenticator.Flush(); err != nil {
+			slog.Error("Error saving API usage", "error", err)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/ner"
+)
+
+// handleBatch extracts entities from many documents in one request. The
+// documents are processed in parallel, as many at a time as there are
+// extractors, and each gets its own result or error, in input order.
+func handleBatch(nerService *ner.Service, maxDocuments, maxBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxBytes))
+		body, err := readBody(c)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Batch exceeds %d bytes", maxBytes)})
+			return
+		}
+		if isCharsetError(err) {
+			c.JSON(http.StatusBadRequest, charsetErrorResponse(err))
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+
+		var req ner.BatchRequest
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+			return
+		}
+		if len(req.Documents) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "At least one document is required"})
+			return
+		}
+		if len(req.Documents) > maxDocuments {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Batch exceeds %d documents", maxDocuments)})
+			return
+		}
+		ids := make(map[string]bool, len(req.Documents))
+		for _, doc := range req.Documents {
+			if doc.ID == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Every document needs an id"})
+				return
+			}
+			if ids[doc.ID] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Duplicate document id %q", doc.ID)})
+				return
+			}
+			ids[doc.ID] = true
+		}
+
+		c.JSON(http.StatusOK, ner.BatchResponse{Results: extractBatch(c.Request.Context(), nerService, req.Documents)})
+	}
+}
+
+// extractBatch processes docs with one goroutine per extractor. Documents
+// not finished when ctx ends fail with its error.
+func extractBatch(ctx context.Context, nerService *ner.Service, docs []ner.BatchDocument) []ner.BatchResult {
+	results := make([]ner.BatchResult, len(docs))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(nerService.PoolStats().Size, len(docs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = extractBatchDocument(ctx, nerService, docs[i])
+			}
+		}()
+	}
+	for i := range docs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
+
+func extractBatchDocument(ctx context.Context, nerService *ner.Service, doc ner.BatchDocument) ner.BatchResult {
+	result := ner.BatchResult{ID: doc.ID}
+	if doc.Text == "" {
+		result.Error = "Text field is required"
+		return result
+	}
+
+	response, cacheStatus, err := extractText(ctx, nerService, doc.Text, doc.Options)
+	if err != nil {
+		_, _, result.Error = extractionError(ctx, err)
+		return result
+	}
+	result.Cache = cacheStatus
+	if doc.Options.Format != formatHTML {
+		response = &ner.ExtractResponse{Entities: response.Entities}
+	}
+	result.ExtractResponse = response
+	return result
+}
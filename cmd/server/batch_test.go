@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ner-service-go/internal/config"
+	"ner-service-go/internal/ner"
+)
+
+func postBatch(t *testing.T, s *testServer, body string) (*httptest.ResponseRecorder, ner.BatchResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/ner/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	var response ner.BatchResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response %s: %v", w.Body.String(), err)
+		}
+	}
+	return w, response
+}
+
+func TestHandleBatch_DocumentErrors(t *testing.T) {
+	s := newTestServer(t, nil, nil)
+
+	w, response := postBatch(t, s, `{"documents":[
+		{"id":"empty","text":""},
+		{"id":"xml","text":"Madrid","options":{"format":"xml"}},
+		{"id":"ok","text":"María García vive en Madrid."}
+	]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if len(response.Results) != 3 {
+		t.Fatalf("Expected 3 results, but got %d", len(response.Results))
+	}
+	if response.Results[0].Error != "Text field is required" {
+		t.Errorf("Expected a missing text error, but got %q", response.Results[0].Error)
+	}
+	if !strings.HasPrefix(response.Results[1].Error, "Unsupported format") {
+		t.Errorf("Expected an unsupported format error, but got %q", response.Results[1].Error)
+	}
+	if ok := response.Results[2]; ok.Error != "" || ok.ExtractResponse == nil || len(ok.Entities) == 0 {
+		t.Errorf("Expected entities for the valid document, but got %+v", ok)
+	}
+}
+
+func TestHandleBatch_PreservesOrder(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) { cfg.PoolSize = 4 }, nil)
+
+	texts := []string{"María García vive en Madrid.", "Pedro Sánchez visitó Barcelona.", "", "Trabajo en Sevilla."}
+	var docs []string
+	for i := range 20 {
+		docs = append(docs, fmt.Sprintf(`{"id":"doc-%d","text":%q}`, i, texts[i%len(texts)]))
+	}
+	w, response := postBatch(t, s, `{"documents":[`+strings.Join(docs, ",")+`]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if len(response.Results) != len(docs) {
+		t.Fatalf("Expected %d results, but got %d", len(docs), len(response.Results))
+	}
+	for i, result := range response.Results {
+		if expected := fmt.Sprintf("doc-%d", i); result.ID != expected {
+			t.Errorf("Expected result %d to be %s, but got %s", i, expected, result.ID)
+		}
+		if empty := texts[i%len(texts)] == ""; empty != (result.Error != "") {
+			t.Errorf("Expected result %s to fail only for an empty text, but got error %q", result.ID, result.Error)
+		}
+	}
+}
+
+func TestHandleBatch_Limits(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.BatchMaxDocuments = 2
+		cfg.BatchMaxBytes = 200
+	}, nil)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"within limits", `{"documents":[{"id":"1","text":"Madrid"},{"id":"2","text":"Sevilla"}]}`, http.StatusOK},
+		{"too many documents", `{"documents":[{"id":"1","text":"a"},{"id":"2","text":"b"},{"id":"3","text":"c"}]}`, http.StatusRequestEntityTooLarge},
+		{"too many bytes", `{"documents":[{"id":"1","text":"` + strings.Repeat("Madrid ", 40) + `"}]}`, http.StatusRequestEntityTooLarge},
+		{"no documents", `{"documents":[]}`, http.StatusBadRequest},
+		{"duplicate ids", `{"documents":[{"id":"1","text":"a"},{"id":"1","text":"b"}]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w, _ := postBatch(t, s, tt.body); w.Code != tt.status {
+				t.Errorf("Expected status %d, but got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// Once the request is canceled, documents not started yet fail instead of
+// being extracted, and the running one stops being waited for.
+func TestExtractBatch_Canceled(t *testing.T) {
+	observer := newGateObserver()
+	s := newTestServer(t, nil, observer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	docs := []ner.BatchDocument{
+		{ID: "1", Text: "María García vive en Madrid."},
+		{ID: "2", Text: "Pedro Sánchez visitó Barcelona."},
+		{ID: "3", Text: "Trabajo en Sevilla."},
+	}
+	done := make(chan []ner.BatchResult)
+	go func() { done <- extractBatch(ctx, s.service, docs) }()
+
+	observer.waitStarted(t)
+	cancel()
+	results := <-done
+	close(observer.release)
+
+	for _, result := range results {
+		if result.Error != "Request was canceled" {
+			t.Errorf("Expected document %s to be canceled, but got %+v", result.ID, result)
+		}
+	}
+	if extra := len(observer.started); extra != 0 {
+		t.Errorf("Expected only the first document to be extracted, but %d more were", extra)
+	}
+}
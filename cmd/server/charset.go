@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"mime"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/charset"
+)
+
+// isContentType reports whether the request body has the given media type,
+// ignoring parameters such as charset.
+func isContentType(contentType, expected string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == expected
+}
+
+// requestCharset returns the encoding declared for the request text: the
+// charset query parameter when set, which overrides detection and the
+// Content-Type header, or the Content-Type charset parameter otherwise.
+func requestCharset(c *gin.Context) string {
+	if override := c.Query("charset"); override != "" {
+		return override
+	}
+	return charset.FromContentType(c.GetHeader("Content-Type"))
+}
+
+// readBody reads the request body and transcodes it to UTF-8.
+func readBody(c *gin.Context) (string, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", err
+	}
+	text, _, err := charset.Decode(body, requestCharset(c))
+	return text, err
+}
+
+// isCharsetError reports whether err comes from decoding the request text, as
+// opposed to reading it.
+func isCharsetError(err error) bool {
+	return errors.Is(err, charset.ErrInvalidUTF8) || errors.Is(err, charset.ErrUnknownCharset)
+}
+
+// charsetErrorResponse is the 400 response for text that cannot be decoded.
+func charsetErrorResponse(err error) gin.H {
+	return gin.H{"error": "Invalid text encoding: " + err.Error()}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutHeader lets a client set a shorter deadline for its request than
+// the server's, in seconds ("2.5") or as a duration ("1500ms").
+const timeoutHeader = "X-Request-Timeout"
+
+var errInvalidTimeout = errors.New("invalid request timeout")
+
+var invalidTimeoutResponse = gin.H{
+	"error": "Invalid " + timeoutHeader + " header, expected seconds or a duration such as 1500ms",
+	"code":  "invalid_timeout",
+}
+
+// withDeadline ends the request context after the server timeout, or the
+// client's timeout when shorter. The context also ends when the client
+// disconnects, and extraction gives up in either case.
+func withDeadline(serverTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout, err := requestTimeout(c, serverTimeout)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, invalidTimeoutResponse)
+			return
+		}
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
+
+// requestTimeout returns the shorter of the server timeout and the one the
+// client asked for, zero meaning no deadline.
+func requestTimeout(c *gin.Context, serverTimeout time.Duration) (time.Duration, error) {
+	value := c.GetHeader(timeoutHeader)
+	if value == "" {
+		return serverTimeout, nil
+	}
+	timeout, err := parseTimeout(value)
+	if err != nil {
+		return 0, err
+	}
+	if serverTimeout > 0 && serverTimeout < timeout {
+		return serverTimeout, nil
+	}
+	return timeout, nil
+}
+
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			return 0, errInvalidTimeout
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+	if timeout <= 0 {
+		return 0, errInvalidTimeout
+	}
+	return timeout, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/auth"
+	"ner-service-go/internal/ner"
+)
+
+var (
+	errUnsupportedFormat    = errors.New("unsupported format")
+	errEntityTypeNotAllowed = errors.New("entity type not allowed")
+)
+
+// extractText extracts entities from text in the format of opts, through
+// the result cache when it is enabled, after charging its characters to the
+// client's quota. The visible text and annotated HTML are only filled in
+// for HTML input, the latter only when opts.Annotate is set.
+func extractText(ctx context.Context, nerService *ner.Service, text string, opts ner.ExtractOptions) (*ner.ExtractResponse, ner.CacheStatus, error) {
+	switch opts.Format {
+	case "", formatText, formatSocial, formatHTML:
+	default:
+		return nil, "", errUnsupportedFormat
+	}
+	types, err := entityTypes(ctx, opts.EntityTypes)
+	if err != nil {
+		return nil, "", err
+	}
+	opts.EntityTypes = types
+
+	if err := chargeQuota(ctx, text); err != nil {
+		return nil, "", err
+	}
+
+	logInput(ctx, text)
+	response, cacheStatus, err := nerService.ExtractCached(ctx, text, opts, func(ctx context.Context) (*ner.ExtractResponse, error) {
+		return extractFormat(ctx, nerService, text, opts)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	logEntities(ctx, response.Entities)
+	return response, cacheStatus, nil
+}
+
+func extractFormat(ctx context.Context, nerService *ner.Service, text string, opts ner.ExtractOptions) (*ner.ExtractResponse, error) {
+	switch opts.Format {
+	case formatSocial:
+		entities, err := nerService.ExtractSocial(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		return &ner.ExtractResponse{Entities: filterEntities(entities, opts.EntityTypes)}, nil
+	case formatHTML:
+		return extractHTML(ctx, nerService, text, opts.Annotate, opts.EntityTypes)
+	default:
+		entities, err := nerService.ExtractEntities(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		return &ner.ExtractResponse{Entities: filterEntities(entities, opts.EntityTypes)}, nil
+	}
+}
+
+// entityTypes returns the entity types to extract for a client: those it
+// requested, or all it may receive when its token restricts them. Requesting
+// a type the client may not receive fails with errEntityTypeNotAllowed.
+func entityTypes(ctx context.Context, requested []string) ([]string, error) {
+	principal := auth.PrincipalFrom(ctx)
+	if principal == nil || len(principal.EntityTypes) == 0 {
+		return requested, nil
+	}
+	if len(requested) == 0 {
+		return principal.EntityTypes, nil
+	}
+	for _, tag := range requested {
+		if !principal.AllowsEntityType(tag) {
+			return nil, fmt.Errorf("%w: %s", errEntityTypeNotAllowed, tag)
+		}
+	}
+	return requested, nil
+}
+
+// filterEntities keeps the entities with one of the given tags, or all of
+// them when no tags are given.
+func filterEntities(entities []ner.Entity, types []string) []ner.Entity {
+	if len(types) == 0 {
+		return entities
+	}
+	kept := entities[:0:0]
+	for _, entity := range entities {
+		if slices.Contains(types, entity.Tag) {
+			kept = append(kept, entity)
+		}
+	}
+	return kept
+}
+
+// setCacheHeader reports whether the result came from the result cache.
+func setCacheHeader(c *gin.Context, status ner.CacheStatus) {
+	if status != "" {
+		c.Header("X-Cache", strings.ToUpper(string(status)))
+	}
+}
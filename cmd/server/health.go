@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/ner"
+)
+
+// Phases of the server's life, reported by the health endpoints.
+const (
+	phaseLoading int32 = iota
+	phaseReady
+	phaseDraining
+)
+
+// serverState tracks the phase of the server and the NER service once it
+// is loaded. The server accepts connections while the model loads, so that
+// liveness probes pass and readiness probes can say why it is not ready.
+type serverState struct {
+	phase   atomic.Int32
+	service atomic.Pointer[ner.Service]
+}
+
+// notReadyReason returns why the server should not receive traffic, or ""
+// when it is ready.
+func (s *serverState) notReadyReason() string {
+	switch s.phase.Load() {
+	case phaseLoading:
+		return "loading"
+	case phaseDraining:
+		return "draining"
+	}
+	if svc := s.service.Load(); svc != nil && svc.Reloading() {
+		return "reloading"
+	}
+	return ""
+}
+
+// switchHandler serves requests with the handler stored last, so that the
+// full router replaces the health-only one once the model is loaded.
+type switchHandler struct {
+	handler atomic.Value
+}
+
+func (h *switchHandler) Store(handler http.Handler) {
+	h.handler.Store(&handler)
+}
+
+func (h *switchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.handler.Load().(*http.Handler)).ServeHTTP(w, r)
+}
+
+// healthRoutes adds the health endpoints, which are served from startup to
+// shutdown.
+func healthRoutes(r *gin.Engine, state *serverState, deepCheckTimeout time.Duration) {
+	r.GET("/health", handleHealth(state))
+	r.GET("/livez", handleLive)
+	r.GET("/readyz", handleReady(state))
+	r.GET("/healthz/deep", withDeadline(deepCheckTimeout), handleDeepHealth(state))
+}
+
+// healthResponse is the body of GET /health.
+type healthResponse struct {
+	// Status is "healthy", "loading" or "draining"
+	Status  string `json:"status"`
+	Service string `json:"service"`
+}
+
+// livenessResponse is the body of GET /livez.
+type livenessResponse struct {
+	Status string `json:"status"`
+}
+
+// readinessResponse is the body of GET /readyz.
+type readinessResponse struct {
+	// Status is "ready" or "not_ready"
+	Status string `json:"status"`
+	// Reason is "loading", "reloading" or "draining" when not ready
+	Reason string `json:"reason,omitempty"`
+}
+
+// deepHealthResponse is the body of GET /healthz/deep.
+type deepHealthResponse struct {
+	Status string `json:"status"`
+	// Model is set once the model is loaded
+	Model  *ner.ModelInfo       `json:"model,omitempty"`
+	Checks []ner.ComponentCheck `json:"checks"`
+}
+
+// handleHealth reports the server healthy while it serves requests, and
+// 503 while the model loads and while it drains requests on shutdown.
+func handleHealth(state *serverState) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch state.phase.Load() {
+		case phaseLoading:
+			c.JSON(http.StatusServiceUnavailable, healthResponse{Status: "loading", Service: "ner-service-go"})
+		case phaseDraining:
+			c.JSON(http.StatusServiceUnavailable, healthResponse{Status: "draining", Service: "ner-service-go"})
+		default:
+			c.JSON(http.StatusOK, healthResponse{Status: "healthy", Service: "ner-service-go"})
+		}
+	}
+}
+
+// handleLive reports that the process is running and serving HTTP. It
+// does not depend on the model, so that a slow load or a busy pool does
+// not get the process restarted.
+func handleLive(c *gin.Context) {
+	c.JSON(http.StatusOK, livenessResponse{Status: "alive"})
+}
+
+// handleReady reports whether the server should receive traffic: 503 while
+// the model loads at startup, while a new model loads and while requests
+// drain on shutdown.
+func handleReady(state *serverState) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if reason := state.notReadyReason(); reason != "" {
+			c.JSON(http.StatusServiceUnavailable, readinessResponse{Status: "not_ready", Reason: reason})
+			return
+		}
+		c.JSON(http.StatusOK, readinessResponse{Status: "ready"})
+	}
+}
+
+// handleDeepHealth runs a canary sentence through the loaded models and
+// checks the pool and the cache, reporting the status and duration of each
+// component. Any failed component makes it 503.
+func handleDeepHealth(state *serverState) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		svc := state.service.Load()
+		if svc == nil {
+			c.JSON(http.StatusServiceUnavailable, deepHealthResponse{
+				Status: ner.CheckFailed,
+				Checks: []ner.ComponentCheck{{Name: "model", Status: ner.CheckFailed, Error: "model is loading"}},
+			})
+			return
+		}
+
+		checks := svc.HealthCheck(c.Request.Context())
+		status, code := ner.CheckOK, http.StatusOK
+		for _, check := range checks {
+			if check.Status != ner.CheckOK {
+				status, code = ner.CheckFailed, http.StatusServiceUnavailable
+			}
+		}
+		model := svc.Model()
+		c.JSON(code, deepHealthResponse{Status: status, Model: &model, Checks: checks})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+
+	"ner-service-go/internal/htmltext"
+	"ner-service-go/internal/ner"
+)
+
+// Input formats accepted by /ner.
+const (
+	formatText   = "text"
+	formatHTML   = "html"
+	formatSocial = "social"
+)
+
+// isHTMLContentType reports whether the request body is an HTML document.
+func isHTMLContentType(contentType string) bool {
+	return isContentType(contentType, "text/html")
+}
+
+// extractHTML extracts entities from the visible text of an HTML document.
+// Each block-level element is processed separately so entities never span
+// block boundaries. Entity offsets refer to the visible text, which is
+// returned with them, and DOM locations to the source text nodes. When types are given,
+// only entities with those tags are returned and annotated.
+func extractHTML(ctx context.Context, nerService *ner.Service, source string, annotate bool, types []string) (*ner.ExtractResponse, error) {
+	doc, err := htmltext.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := []ner.Entity{}
+	for _, block := range doc.Blocks {
+		found, err := nerService.ExtractEntities(ctx, doc.Text[block.Start:block.End])
+		if err != nil {
+			return nil, err
+		}
+		for _, entity := range found {
+			entity.Start += block.Start
+			entity.End += block.Start
+			entity.DOM = doc.Locate(entity.Start, entity.End)
+			entities = append(entities, entity)
+		}
+	}
+
+	entities = filterEntities(entities, types)
+	response := &ner.ExtractResponse{
+		Entities: entities,
+		Text:     doc.Text,
+	}
+
+	if annotate {
+		spans := make([]htmltext.Span, len(entities))
+		for i, entity := range entities {
+			spans[i] = htmltext.Span{Start: entity.Start, End: entity.End, Tag: entity.Tag}
+		}
+		response.AnnotatedHTML, err = doc.Annotate(spans)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
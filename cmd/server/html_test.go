@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ner-service-go/internal/ner"
+)
+
+func TestHandleNER_HTMLReturnsVisibleText(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/ner", strings.NewReader("<p>María García vive en <b>Madrid</b>.</p>"))
+	req.Header.Set("Content-Type", "text/html")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response ner.ExtractResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response %s: %v", w.Body.String(), err)
+	}
+	if len(response.Entities) == 0 {
+		t.Fatalf("Expected entities, but got none")
+	}
+	for _, entity := range response.Entities {
+		if got := response.Text[entity.Start:entity.End]; got != entity.Label {
+			t.Errorf("Expected offsets to point at %q in the visible text, but got %q", entity.Label, got)
+		}
+	}
+	if response.AnnotatedHTML != "" {
+		t.Errorf("Expected no annotated HTML without annotate, but got %q", response.AnnotatedHTML)
+	}
+}
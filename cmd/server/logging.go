@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"ner-service-go/internal/config"
+	"ner-service-go/internal/ner"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID matches request IDs taken from clients. Others are replaced
+// by a generated one, so that headers cannot inject content into the logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// maxLoggedText is how much of an input text is logged in PII mode.
+const maxLoggedText = 1000
+
+// quietRoutes are polled by probes and scrapers, and logged at debug level
+// so that they do not drown the requests.
+var quietRoutes = map[string]bool{
+	"/health":       true,
+	"/livez":        true,
+	"/readyz":       true,
+	"/healthz/deep": true,
+	"/metrics":      true,
+}
+
+// newLogger returns a JSON logger writing to stderr at the configured
+// level.
+func newLogger(cfg *config.Config) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		level = slog.LevelInfo
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+}
+
+// requestLog collects what the handlers of a request extracted, for its
+// log line. Batch and stream documents are extracted concurrently.
+type requestLog struct {
+	logger *slog.Logger
+	// pii allows input texts and entity labels in the logs
+	pii bool
+
+	mu         sync.Mutex
+	characters int
+	entities   int
+	tags       map[string]int
+	texts      []string
+	labels     []string
+}
+
+type requestLogKey struct{}
+
+func requestLogFrom(ctx context.Context) *requestLog {
+	l, _ := ctx.Value(requestLogKey{}).(*requestLog)
+	return l
+}
+
+// loggerFrom returns the logger of the request ctx belongs to, which adds
+// its request ID to every record, or the default logger outside requests.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if l := requestLogFrom(ctx); l != nil {
+		return l.logger
+	}
+	return slog.Default()
+}
+
+// piiAttr returns an attribute with personal data, such as a file name, or
+// an empty attribute, which is not logged, unless PII logging is enabled.
+func piiAttr(ctx context.Context, key, value string) slog.Attr {
+	if l := requestLogFrom(ctx); l == nil || !l.pii {
+		return slog.Attr{}
+	}
+	return slog.String(key, value)
+}
+
+// logInput records the size of a text extracted for the request of ctx.
+func logInput(ctx context.Context, text string) {
+	l := requestLogFrom(ctx)
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.characters += utf8.RuneCountInString(text)
+	if l.pii {
+		if len(text) > maxLoggedText {
+			text = strings.ToValidUTF8(text[:maxLoggedText], "") + "..."
+		}
+		l.texts = append(l.texts, text)
+	}
+}
+
+// logEntities records the entities returned for the request of ctx.
+func logEntities(ctx context.Context, entities []ner.Entity) {
+	l := requestLogFrom(ctx)
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entities += len(entities)
+	for _, entity := range entities {
+		l.tags[entity.Tag]++
+		if l.pii {
+			l.labels = append(l.labels, entity.Label)
+		}
+	}
+}
+
+// logRequests gives every request an ID, taken from its X-Request-ID header
+// or generated, and returned in the response. When the request is done it
+// logs its route, status, latency, input size and entity counts. Input
+// texts and entity labels are only logged when pii is set.
+func logRequests(logger *slog.Logger, pii bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Header(requestIDHeader, id)
+
+		l := &requestLog{
+			logger: logger.With("request_id", id),
+			pii:    pii,
+			tags:   make(map[string]int),
+		}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestLogKey{}, l))
+		c.Next()
+
+		route := c.FullPath()
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case quietRoutes[route]:
+			level = slog.LevelDebug
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", span.TraceID().String()))
+		}
+		l.mu.Lock()
+		if l.characters > 0 {
+			attrs = append(attrs, slog.Int("input_length", l.characters), slog.Int("entities", l.entities))
+			if len(l.tags) > 0 {
+				attrs = append(attrs, slog.Any("entity_tags", l.tags))
+			}
+		}
+		if pii && len(l.texts) > 0 {
+			attrs = append(attrs, slog.Any("texts", l.texts), slog.Any("entity_labels", l.labels))
+		}
+		l.mu.Unlock()
+		l.logger.LogAttrs(c.Request.Context(), level, "Request", attrs...)
+	}
+}
+
+// recoverPanics turns a panicking handler into a 500 response, and logs the
+// panic with the request ID.
+func recoverPanics() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		loggerFrom(c.Request.Context()).Error("Panic serving request", "panic", recovered, "stack", string(debug.Stack()))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error", "code": "internal"})
+	})
+}
+
+// fatal logs an error that prevents the server from running, and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
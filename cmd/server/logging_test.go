@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logRequest serves one extraction with a router logging to a buffer, and
+// returns the log output.
+func logRequest(t *testing.T, pii bool, text string) string {
+	t.Helper()
+	s := newTestServer(t, nil, nil)
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	r := gin.New()
+	r.Use(logRequests(logger, pii))
+	r.POST("/ner", handleNER(s.service))
+
+	req := httptest.NewRequest(http.MethodPost, "/ner", strings.NewReader(text))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set(requestIDHeader, "test-request-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if id := w.Header().Get(requestIDHeader); id != "test-request-1" {
+		t.Errorf("Expected the request ID to be returned, but got %q", id)
+	}
+	return logs.String()
+}
+
+func TestLogRequests_KeepsTextsOut(t *testing.T) {
+	text := "María García vive en Madrid."
+	logs := logRequest(t, false, text)
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(logs)), &record); err != nil {
+		t.Fatalf("Expected one JSON log record, but got %s: %v", logs, err)
+	}
+	if record["request_id"] != "test-request-1" {
+		t.Errorf("Expected the request ID in the log, but got %s", logs)
+	}
+	if record["entities"] != float64(2) {
+		t.Errorf("Expected the entity count in the log, but got %s", logs)
+	}
+	for _, secret := range []string{"María", "García", "Madrid", "vive"} {
+		if strings.Contains(logs, secret) {
+			t.Errorf("Expected %q to stay out of the log, but got %s", secret, logs)
+		}
+	}
+}
+
+func TestLogRequests_PII(t *testing.T) {
+	logs := logRequest(t, true, "María García vive en Madrid.")
+
+	if !strings.Contains(logs, "María García vive en Madrid.") || !strings.Contains(logs, "test-request-1") {
+		t.Errorf("Expected the text and the request ID in the log with PII logging on, but got %s", logs)
+	}
+}
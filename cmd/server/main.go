@@ -1,60 +1,293 @@
 package main
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/auth"
+	"ner-service-go/internal/charset"
 	"ner-service-go/internal/config"
+	"ner-service-go/internal/metrics"
 	"ner-service-go/internal/ner"
+	"ner-service-go/internal/pool"
+	"ner-service-go/internal/tlsconfig"
+	"ner-service-go/internal/tracing"
 	"ner-service-go/internal/version"
 )
 
 func main() {
 	cfg := config.Load()
+	slog.SetDefault(newLogger(cfg))
 
-	nerService, err := ner.NewService(cfg.ModelPath)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
 	if err != nil {
-		log.Fatalf("Failed to initialize NER service: %v", err)
+		fatal("Failed to initialize tracing", err)
+	}
+
+	// Extraction endpoints require an API key, a JWT or a client
+	// certificate when any of them is configured
+	authOpts, err := authOptions(cfg)
+	if err != nil {
+		fatal("Failed to load JWT scopes", err)
+	}
+	var authenticator *auth.Authenticator
+	if authOpts.KeysFile != "" || authOpts.JWT != nil || cfg.TLSClientCAFile != "" {
+		authenticator, err = auth.New(authOpts)
+		if err != nil {
+			fatal("Failed to initialize authentication", err)
+		}
+		go flushUsage(authenticator)
 	}
-	defer nerService.Close()
 
-	r := gin.Default()
+	// Only the health and metrics endpoints are served until the model is
+	// loaded
+	state := &serverState{}
+	serviceMetrics := metrics.New()
+	boot := newEngine(cfg, serviceMetrics)
+	healthRoutes(boot, state, cfg.RequestTimeout)
+	boot.GET("/metrics", gin.WrapH(serviceMetrics.Handler()))
+	boot.NoRoute(func(c *gin.Context) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Model is loading, try again later", "code": "loading"})
+	})
+	handler := &switchHandler{}
+	handler.Store(boot)
 
-	r.GET("/health", handleHealth)
+	// Reloads are stopped before the service is closed, so none loads a
+	// model after the extractors are freed
+	reloads, stopReloads := context.WithCancel(context.Background())
+	loaded := make(chan *ner.Service, 1)
+	go func() {
+		opts := ner.OptionsFromConfig(cfg)
+		opts.Observer = serviceMetrics
+		nerService, err := ner.NewService(opts)
+		if err != nil {
+			fatal("Failed to initialize NER service", err)
+		}
+		serviceMetrics.WatchService(nerService)
+		handler.Store(newRouter(cfg, nerService, authenticator, state, serviceMetrics))
+		state.service.Store(nerService)
+		state.phase.CompareAndSwap(phaseLoading, phaseReady)
+		slog.Info("Model loaded, serving requests", "load_seconds", nerService.Model().LoadSeconds)
+		loaded <- nerService
+
+		// The model can be replaced without a restart on SIGHUP, from the
+		// admin endpoint and when its file changes
+		go reloadOnSignal(reloads, nerService)
+		if cfg.ModelWatchInterval > 0 {
+			go watchModel(reloads, nerService, cfg.BackendModelPath(), cfg.ModelWatchInterval)
+		}
+	}()
+
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: handler}
+	useTLS := cfg.TLSCertFile != ""
+	if useTLS {
+		certificates, err := tlsconfig.New(tlsOptions(cfg))
+		if err != nil {
+			fatal("Failed to load TLS configuration", err)
+		}
+		server.TLSConfig = certificates.TLSConfig()
+		go watchCertificates(certificates)
+	}
+	slog.Info("Server starting", "port", cfg.Port, "tls", useTLS)
+	if err := serveUntilSignal(server, useTLS, state, cfg.ShutdownGracePeriod); err != nil {
+		fatal("Failed to start server", err)
+	}
+
+	// No request is using the extractors anymore: free them, waiting for
+	// calls abandoned by canceled requests and for a reload in progress to
+	// return. A model still loading is dropped with the process.
+	stopReloads()
+	select {
+	case nerService := <-loaded:
+		nerService.Close()
+	default:
+		slog.Warn("Stopped before the model finished loading")
+	}
+	if authenticator != nil {
+		if err := authenticator.Flush(); err != nil {
+			slog.Error("Error saving API usage", "error", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Error exporting the last spans", "error", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// newEngine returns a router that logs, traces and measures requests.
+// Panics are recovered innermost, so that they are recorded as 500s.
+func newEngine(cfg *config.Config, serviceMetrics *metrics.Metrics) *gin.Engine {
+	r := gin.New()
+	r.Use(logRequests(slog.Default(), cfg.LogPII), tracing.Middleware(), serviceMetrics.Middleware(), recoverPanics())
+	return r
+}
+
+// newRouter returns the router serving every endpoint with nerService.
+func newRouter(cfg *config.Config, nerService *ner.Service, authenticator *auth.Authenticator, state *serverState, serviceMetrics *metrics.Metrics) *gin.Engine {
+	r := newEngine(cfg, serviceMetrics)
+
+	healthRoutes(r, state, cfg.RequestTimeout)
 	r.GET("/version", handleVersion)
-	r.POST("/ner", handleNER(nerService))
+	r.GET("/stats", handleStats(nerService))
+	r.GET("/metrics", gin.WrapH(serviceMetrics.Handler()))
+	r.GET("/openapi.json", handleOpenAPI(apiDocument(cfg)))
+	r.GET("/docs", handleExplorer)
+
+	api := r.Group("/")
+	if authenticator != nil {
+		api.Use(requireAuth(authenticator, cfg.Backend))
+	}
+
+	// Single texts are interactive and served before batch and stream
+	// documents
+	interactive := shedLoad(nerService, pool.PriorityHigh, cfg.ShedQueueDepth, cfg.ShedMaxWait)
+	bulk := shedLoad(nerService, pool.PriorityLow, cfg.ShedQueueDepth, cfg.ShedMaxWait)
+	api.POST("/ner", interactive, withDeadline(cfg.RequestTimeout), handleNER(nerService))
+	api.POST("/ner/batch", bulk, withDeadline(cfg.RequestTimeout), handleBatch(nerService, cfg.BatchMaxDocuments, cfg.BatchMaxBytes))
+	// Streams last as long as the client keeps sending, so the deadline
+	// applies to each document instead
+	api.POST("/ner/stream", bulk, handleStream(nerService, cfg.StreamMaxLineBytes, cfg.RequestTimeout))
 
-	log.Printf("Server starting on port %s", cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	if cfg.AdminToken != "" {
+		r.POST("/admin/reload", requireAdmin(cfg.AdminToken), handleReload(nerService))
 	}
+	return r
 }
 
 func handleNER(nerService *ner.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var text string
-		
+		format := c.Query("format")
+		annotate, _ := strconv.ParseBool(c.Query("annotate"))
+		var types []string
+		if t := c.Query("entity_types"); t != "" {
+			types = strings.Split(t, ",")
+		}
+
 		// Try to get text from different sources
 		contentType := c.GetHeader("Content-Type")
-		
-		if contentType == "application/json" || contentType == "application/json; charset=utf-8" {
+
+		if isHTMLContentType(contentType) {
+			// Handle a raw HTML document
+			body, err := readBody(c)
+			if isCharsetError(err) {
+				c.JSON(http.StatusBadRequest, charsetErrorResponse(err))
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				return
+			}
+			text = body
+			format = formatHTML
+		} else if isContentType(contentType, "text/plain") {
+			// Handle raw text
+			body, err := readBody(c)
+			if isCharsetError(err) {
+				c.JSON(http.StatusBadRequest, charsetErrorResponse(err))
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				return
+			}
+			text = body
+			// JSON sent as text/plain was read as an ExtractRequest before
+			// raw text was accepted, so it still is
+			var req ner.ExtractRequest
+			if json.Unmarshal([]byte(body), &req) == nil && req.Text != "" {
+				text = req.Text
+				if req.Format != "" {
+					format = req.Format
+				}
+				annotate = annotate || req.Annotate
+				if len(req.EntityTypes) > 0 {
+					types = req.EntityTypes
+				}
+			}
+		} else if isContentType(contentType, "application/json") {
 			// Handle JSON input
+			body, err := readBody(c)
+			if isCharsetError(err) {
+				c.JSON(http.StatusBadRequest, charsetErrorResponse(err))
+				return
+			}
 			var req ner.ExtractRequest
-			if err := c.ShouldBindJSON(&req); err != nil {
+			if err != nil || json.Unmarshal([]byte(body), &req) != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
 				return
 			}
 			text = req.Text
+			if req.Format != "" {
+				format = req.Format
+			}
+			annotate = annotate || req.Annotate
+			if len(req.EntityTypes) > 0 {
+				types = req.EntityTypes
+			}
+		} else if file, err := c.FormFile("file"); err == nil {
+			// Handle a document upload (multipart/form-data with a file field)
+			entities, err := extractUpload(c.Request.Context(), nerService, file, c.Query("charset"), types)
+			if errors.Is(err, errUploadTooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Uploaded file is too large"})
+				return
+			}
+			if isCharsetError(err) {
+				c.JSON(http.StatusBadRequest, charsetErrorResponse(err))
+				return
+			}
+			if errors.Is(err, pool.ErrQueueFull) || errors.Is(err, auth.ErrQuotaExceeded) || errors.Is(err, errEntityTypeNotAllowed) || ner.IsContextError(err) {
+				respondExtractionError(c, err)
+				return
+			}
+			if err != nil {
+				loggerFrom(c.Request.Context()).Warn("Failed to read uploaded document", "error", err, piiAttr(c.Request.Context(), "filename", file.Filename))
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded document"})
+				return
+			}
+
+			c.JSON(http.StatusOK, entities)
+			return
 		} else {
 			// Handle form data (application/x-www-form-urlencoded or multipart/form-data)
 			text = c.PostForm("text")
-			
+			if f := c.PostForm("format"); f != "" {
+				format = f
+			}
+			if text != "" {
+				decoded, _, err := charset.DecodeString(text, requestCharset(c))
+				if err != nil {
+					c.JSON(http.StatusBadRequest, charsetErrorResponse(err))
+					return
+				}
+				text = decoded
+			}
+
 			// If not found in form data, try to bind as JSON anyway (fallback)
 			if text == "" {
 				var req ner.ExtractRequest
 				if err := c.ShouldBindJSON(&req); err == nil {
 					text = req.Text
+					if req.Format != "" {
+						format = req.Format
+					}
+					annotate = annotate || req.Annotate
+					if len(req.EntityTypes) > 0 {
+						types = req.EntityTypes
+					}
 				}
 			}
 		}
@@ -64,22 +297,60 @@ func handleNER(nerService *ner.Service) gin.HandlerFunc {
 			return
 		}
 
-		entities, err := nerService.ExtractEntities(text)
+		response, cacheStatus, err := extractText(c.Request.Context(), nerService, text, ner.ExtractOptions{Format: format, Annotate: annotate, EntityTypes: types})
 		if err != nil {
-			log.Printf("Error extracting entities: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to extract entities"})
+			respondExtractionError(c, err)
 			return
 		}
+		setCacheHeader(c, cacheStatus)
+
+		// HTML input gets the visible text its entity offsets refer to
+		if format == formatHTML {
+			c.JSON(http.StatusOK, response)
+		} else {
+			c.JSON(http.StatusOK, response.Entities)
+		}
+	}
+}
 
-		c.JSON(http.StatusOK, entities)
+// respondExtractionError reports a failed extraction with a message and a
+// machine-readable code.
+func respondExtractionError(c *gin.Context, err error) {
+	status, code, message := extractionError(c.Request.Context(), err)
+	c.JSON(status, gin.H{"error": message, "code": code})
+}
+
+// extractionError returns the status code, error code and message for a
+// failed extraction: 400 for an unknown format, 403 for entity types the
+// client may not receive, 429 when the client's character quota is used up,
+// 503 when every extractor is busy and the queue is full or the client went
+// away, 504 when the deadline passed, and 500 otherwise.
+func extractionError(ctx context.Context, err error) (int, string, string) {
+	switch {
+	case errors.Is(err, errUnsupportedFormat):
+		return http.StatusBadRequest, "unsupported_format", "Unsupported format, expected text, html or social"
+	case errors.Is(err, errEntityTypeNotAllowed):
+		return http.StatusForbidden, "forbidden", "Entity type not allowed for this client" + strings.TrimPrefix(err.Error(), errEntityTypeNotAllowed.Error())
+	case errors.Is(err, pool.ErrQueueFull):
+		return http.StatusServiceUnavailable, "queue_full", "Server is busy, try again later"
+	case errors.Is(err, auth.ErrQuotaExceeded):
+		return http.StatusTooManyRequests, "quota_exceeded", "Character quota exceeded" + strings.TrimPrefix(err.Error(), auth.ErrQuotaExceeded.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "deadline_exceeded", "Extraction did not finish before the deadline"
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable, "canceled", "Request was canceled"
+	default:
+		loggerFrom(ctx).Error("Error extracting entities", "error", err)
+		return http.StatusInternalServerError, "internal", "Failed to extract entities"
 	}
 }
 
-func handleHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status": "healthy",
-		"service": "ner-service-go",
-	})
+// handleStats reports extractor pool usage: queue depth, wait time and
+// worker utilization, and result cache hits and misses.
+func handleStats(nerService *ner.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, nerService.Stats())
+	}
 }
 
 func handleVersion(c *gin.Context) {
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ner-service-go/internal/ner"
+)
+
+func TestHandleNER_PlainText(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{"raw text", "María García vive en Madrid.", []string{"María García", "Madrid"}},
+		// JSON sent as text/plain went through the JSON fallback before raw
+		// text was accepted
+		{"json", `{"text": "María García vive en Madrid.", "entity_types": ["LOCATION"]}`, []string{"Madrid"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/ner", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "text/plain")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			var entities []ner.Entity
+			if err := json.Unmarshal(w.Body.Bytes(), &entities); err != nil {
+				t.Fatalf("Failed to decode response %s: %v", w.Body.String(), err)
+			}
+			var labels []string
+			for _, entity := range entities {
+				labels = append(labels, entity.Label)
+			}
+			if strings.Join(labels, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected entities %q, but got %q", tt.expected, labels)
+			}
+		})
+	}
+}
+
+// gateObserver holds the extractor of every extraction until release is
+// closed, after sending the extraction on started.
+type gateObserver struct {
+	started chan ner.Extraction
+	release chan struct{}
+}
+
+func newGateObserver() *gateObserver {
+	return &gateObserver{started: make(chan ner.Extraction, 100), release: make(chan struct{})}
+}
+
+func (o *gateObserver) ObserveExtraction(e ner.Extraction) {
+	o.started <- e
+	<-o.release
+}
+
+// waitStarted returns the next extraction holding an extractor.
+func (o *gateObserver) waitStarted(t *testing.T) ner.Extraction {
+	t.Helper()
+	select {
+	case e := <-o.started:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for an extraction")
+		return ner.Extraction{}
+	}
+}
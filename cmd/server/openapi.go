@@ -0,0 +1,332 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/config"
+	"ner-service-go/internal/ner"
+	"ner-service-go/internal/openapi"
+	"ner-service-go/internal/version"
+)
+
+// explorerPage browses and tries out the API described by /openapi.json. It
+// is self-contained, so it works without internet access.
+//
+//go:embed explorer.html
+var explorerPage []byte
+
+// errorResponse is the body of every error response.
+type errorResponse struct {
+	Error string `json:"error"`
+	// Code is a machine-readable error code, set on most errors
+	Code string `json:"code,omitempty"`
+}
+
+// Descriptions of the error statuses the endpoints answer with.
+var errorDescriptions = map[int]string{
+	http.StatusBadRequest:            "Invalid request: missing text, invalid JSON, unsupported format, undecodable text encoding or invalid `X-Request-Timeout` header",
+	http.StatusUnauthorized:          "Missing, unknown, revoked or expired API key or bearer token (`unauthorized`)",
+	http.StatusForbidden:             "The token's scopes do not allow the model or an entity type (`forbidden`)",
+	http.StatusRequestEntityTooLarge: "The upload or batch exceeds the size limits",
+	http.StatusUnsupportedMediaType:  "The body is not `application/x-ndjson`",
+	http.StatusTooManyRequests:       "Rate limit (`rate_limited`) or character quota (`quota_exceeded`) exceeded, or the server is shedding load (`overloaded`). `Retry-After` gives the seconds to wait",
+	http.StatusInternalServerError:   "The extraction failed (`internal`)",
+	http.StatusServiceUnavailable:    "Every extractor is busy and the queue is full (`queue_full`), the request was canceled (`canceled`) or the model is still loading (`loading`)",
+	http.StatusGatewayTimeout:        "The extraction did not finish before the deadline (`deadline_exceeded`)",
+}
+
+// apiDocument describes the endpoints newRouter serves for cfg.
+func apiDocument(cfg *config.Config) *openapi.Document {
+	d := openapi.New(openapi.Info{
+		Title:   "NER Service",
+		Version: version.GetBuildInfo().Version,
+		Description: "Named entity recognition for Spanish text, HTML, social media posts and documents. " +
+			"Every response carries an `X-Request-ID` header, taken from the request when it sends a valid one, that identifies the request in the server logs.",
+	})
+	d.Tags = []openapi.Tag{
+		{Name: "extraction", Description: "Entity extraction"},
+		{Name: "operations", Description: "Health, metrics and administration"},
+	}
+	describeSchemas(d)
+
+	var security []openapi.SecurityRequirement
+	if cfg.APIKeysFile != "" || cfg.JWTJWKS != "" || cfg.TLSClientCAFile != "" {
+		security = []openapi.SecurityRequirement{{"bearer": {}}, {"apiKey": {}}, {"mutualTLS": {}}}
+	}
+	d.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		"bearer":     {Type: "http", Scheme: "bearer", Description: "An API key or, with JWT authentication configured, a JWT"},
+		"apiKey":     {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "An API key"},
+		"mutualTLS":  {Type: "mutualTLS", Description: "A client certificate signed by the configured client CA"},
+		"adminToken": {Type: "http", Scheme: "bearer", Description: "The admin token"},
+	}
+
+	timeoutParam := openapi.Parameter{Name: timeoutHeader, In: "header", Schema: &openapi.Schema{Type: "string"},
+		Description: "Seconds, or a duration such as `1500ms`, to wait for the result. The server timeout applies when shorter"}
+	formatParam := openapi.Parameter{Name: "format", In: "query", Schema: formatSchema(),
+		Description: "Input format. `html` extracts from the visible text, `social` resolves hashtags, handles and emoji"}
+	entityTypesParam := openapi.Parameter{Name: "entity_types", In: "query", Schema: &openapi.Schema{Type: "string"},
+		Description: "Comma-separated entity tags to return, such as `PERSON,LOCATION`"}
+
+	d.Add(http.MethodPost, "/ner", &openapi.Operation{
+		OperationID: "extractEntities",
+		Summary:     "Extract entities from a text, an HTML page or an uploaded document",
+		Description: "The input is read according to the `Content-Type` header:\n\n" +
+			"- `application/json`: an `ExtractRequest`\n" +
+			"- `text/plain`: the text itself, or an `ExtractRequest` when the body is a JSON object with a `text` field\n" +
+			"- `text/html`: an HTML document, as with `format=html`\n" +
+			"- `multipart/form-data` with a `file` field: a DOCX, ODT, PDF, RTF, EPUB, HTML or text document, up to 50 MB. Entities carry their page and paragraph when the format has them\n" +
+			"- `application/x-www-form-urlencoded` or `multipart/form-data` with a `text` field: the text, with an optional `format` field\n\n" +
+			"Any other content type is read as a form, and then as JSON when the form has no `text`. " +
+			"Bodies in other encodings than UTF-8 are transcoded, according to the `charset` parameter, the `Content-Type` charset, a byte order mark or detection. " +
+			"Entity offsets are byte offsets into the UTF-8 text, or into the visible text for HTML.",
+		Tags: []string{"extraction"},
+		Parameters: []openapi.Parameter{
+			formatParam,
+			{Name: "annotate", In: "query", Schema: &openapi.Schema{Type: "boolean"},
+				Description: "For HTML input, also return the HTML with entities wrapped in `<span>` tags"},
+			entityTypesParam,
+			{Name: "charset", In: "query", Schema: &openapi.Schema{Type: "string"},
+				Description: "Encoding of the body, such as `iso-8859-1`, overriding the `Content-Type` charset and detection"},
+			timeoutParam,
+		},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{
+				"application/json": {
+					Schema:  d.SchemaOf(ner.ExtractRequest{}),
+					Example: ner.ExtractRequest{Text: "María García vive en Madrid y trabaja en Telefónica."},
+				},
+				"text/plain": {
+					Schema:  &openapi.Schema{Type: "string"},
+					Example: "El presidente Pedro Sánchez visitó Barcelona.",
+				},
+				"text/html": {
+					Schema:  &openapi.Schema{Type: "string"},
+					Example: "<html><body><h1>Noticias</h1><p>María García vive en Madrid.</p></body></html>",
+				},
+				"application/x-www-form-urlencoded": {
+					Schema: formSchema(false),
+				},
+				"multipart/form-data": {
+					Schema: formSchema(true),
+				},
+			},
+		},
+		Responses: withErrors(d, map[string]*openapi.Response{
+			"200": {
+				Description: "The entities found, or for HTML input the entities with the visible text their offsets refer to, and the annotated HTML when requested",
+				Headers:     cacheHeaders(),
+				Content: jsonContent(&openapi.Schema{OneOf: []*openapi.Schema{
+					d.SchemaOf([]ner.Entity{}),
+					d.SchemaOf(ner.ExtractResponse{}),
+				}}),
+			},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge,
+			http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusGatewayTimeout),
+		Security: security,
+	})
+
+	d.Add(http.MethodPost, "/ner/batch", &openapi.Operation{
+		OperationID: "extractBatch",
+		Summary:     "Extract entities from many documents",
+		Description: fmt.Sprintf("Documents are processed in parallel, and each gets its own result or error, in input order. "+
+			"A batch holds up to %d documents and %d bytes.", cfg.BatchMaxDocuments, cfg.BatchMaxBytes),
+		Tags:       []string{"extraction"},
+		Parameters: []openapi.Parameter{timeoutParam},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{
+				"application/json": {
+					Schema: d.SchemaOf(ner.BatchRequest{}),
+					Example: ner.BatchRequest{Documents: []ner.BatchDocument{
+						{ID: "1", Text: "María García vive en Madrid."},
+						{ID: "2", Text: "<p>Pedro Sánchez visitó Barcelona.</p>", Options: ner.ExtractOptions{Format: formatHTML}},
+					}},
+				},
+			},
+		},
+		Responses: withErrors(d, map[string]*openapi.Response{
+			"200": {Description: "One result per document", Content: jsonContent(d.SchemaOf(ner.BatchResponse{}))},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge,
+			http.StatusTooManyRequests),
+		Security: security,
+	})
+
+	d.Add(http.MethodPost, "/ner/stream", &openapi.Operation{
+		OperationID: "extractStream",
+		Summary:     "Extract entities from a stream of documents",
+		Description: "The body is newline-delimited JSON, one `BatchDocument` per line, and the response one result per line, written while the body is still being read. " +
+			"Lines that cannot be read get a result with an error, and a line that stops the stream gets an error line without an id. " +
+			fmt.Sprintf("Lines are limited to %d bytes. The request timeout applies to each document.", cfg.StreamMaxLineBytes),
+		Tags: []string{"extraction"},
+		Parameters: []openapi.Parameter{
+			{Name: "order", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{streamOrderInput, streamOrderCompleted}},
+				Description: "Write results in input order (the default) or as they complete"},
+			timeoutParam,
+		},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{
+				"application/x-ndjson": {
+					Schema:  d.SchemaOf(ner.BatchDocument{}),
+					Example: "{\"id\":\"1\",\"text\":\"María García vive en Madrid.\"}\n{\"id\":\"2\",\"text\":\"Pedro Sánchez visitó Barcelona.\"}\n",
+				},
+			},
+		},
+		Responses: withErrors(d, map[string]*openapi.Response{
+			"200": {
+				Description: "One line per document",
+				Content: map[string]openapi.MediaType{"application/x-ndjson": {Schema: &openapi.Schema{OneOf: []*openapi.Schema{
+					d.SchemaOf(ner.BatchResult{}),
+					d.SchemaOf(errorResponse{}),
+				}}}},
+			},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusUnsupportedMediaType,
+			http.StatusTooManyRequests),
+		Security: security,
+	})
+
+	operation := func(method, path, id, summary, description string, responses map[string]*openapi.Response) {
+		d.Add(method, path, &openapi.Operation{
+			OperationID: id,
+			Summary:     summary,
+			Description: description,
+			Tags:        []string{"operations"},
+			Responses:   responses,
+		})
+	}
+	health := jsonContent(d.SchemaOf(healthResponse{}))
+	operation(http.MethodGet, "/health", "getHealth", "Report whether the server serves requests", "",
+		map[string]*openapi.Response{
+			"200": {Description: "Serving requests", Content: health},
+			"503": {Description: "The model is loading or requests are draining on shutdown", Content: health},
+		})
+	operation(http.MethodGet, "/livez", "getLiveness", "Report that the process is alive", "Does not depend on the model, for liveness probes.",
+		map[string]*openapi.Response{"200": {Description: "Alive", Content: jsonContent(d.SchemaOf(livenessResponse{}))}})
+	readiness := jsonContent(d.SchemaOf(readinessResponse{}))
+	operation(http.MethodGet, "/readyz", "getReadiness", "Report whether the server should receive traffic", "",
+		map[string]*openapi.Response{
+			"200": {Description: "Ready", Content: readiness},
+			"503": {Description: "The model is loading or reloading, or requests are draining", Content: readiness},
+		})
+	deepHealth := jsonContent(d.SchemaOf(deepHealthResponse{}))
+	operation(http.MethodGet, "/healthz/deep", "getDeepHealth", "Check the model, truecaser, pool and cache",
+		"Runs a canary sentence through the loaded models and reports the status and timing of each component.",
+		map[string]*openapi.Response{
+			"200": {Description: "Every component works", Content: deepHealth},
+			"503": {Description: "A component failed", Content: deepHealth},
+		})
+	operation(http.MethodGet, "/version", "getVersion", "Report the service version", "",
+		map[string]*openapi.Response{"200": {Description: "The version", Content: jsonContent(d.SchemaOf(version.BuildInfo{}))}})
+	operation(http.MethodGet, "/stats", "getStats", "Report the model, extractor pool and cache usage", "",
+		map[string]*openapi.Response{"200": {Description: "Usage statistics", Content: jsonContent(d.SchemaOf(ner.Stats{}))}})
+	operation(http.MethodGet, "/metrics", "getMetrics", "Export metrics in the Prometheus exposition format", "",
+		map[string]*openapi.Response{"200": {Description: "Metrics", Content: map[string]openapi.MediaType{
+			"text/plain": {Schema: &openapi.Schema{Type: "string"}},
+		}}})
+	operation(http.MethodGet, "/openapi.json", "getOpenAPI", "Describe the API in OpenAPI 3.1", "",
+		map[string]*openapi.Response{"200": {Description: "This document", Content: jsonContent(&openapi.Schema{Type: "object"})}})
+	operation(http.MethodGet, "/docs", "getExplorer", "Browse and try out the API", "",
+		map[string]*openapi.Response{"200": {Description: "The API explorer", Content: map[string]openapi.MediaType{
+			"text/html": {Schema: &openapi.Schema{Type: "string"}},
+		}}})
+
+	if cfg.AdminToken != "" {
+		d.Add(http.MethodPost, "/admin/reload", &openapi.Operation{
+			OperationID: "reloadModel",
+			Summary:     "Reload the model file",
+			Description: "Answers once the new model serves requests. When it cannot be loaded or fails its probe, the previous model stays in use.",
+			Tags:        []string{"operations"},
+			Responses: map[string]*openapi.Response{
+				"200": {Description: "The model now serving requests", Content: jsonContent(d.SchemaOf(ner.ModelInfo{}))},
+				"401": {Description: "Missing or wrong admin token (`unauthorized`)", Content: jsonContent(d.SchemaOf(errorResponse{}))},
+				"409": {Description: "Another reload is in progress (`reload_in_progress`)", Content: jsonContent(d.SchemaOf(errorResponse{}))},
+				"422": {Description: "The new model was rejected (`reload_failed`)", Content: jsonContent(d.SchemaOf(errorResponse{}))},
+			},
+			Security: []openapi.SecurityRequirement{{"adminToken": {}}},
+		})
+	}
+	return d
+}
+
+// describeSchemas documents the types whose schemas need more than their
+// field names.
+func describeSchemas(d *openapi.Document) {
+	d.Describe(ner.Entity{}, "An entity found in the input. `start` and `end` are byte offsets, `dom` locates it in HTML text nodes, "+
+		"`page` and `paragraph` in uploaded documents, and `name` is the entity a social media handle stands for.")
+	d.Describe(ner.ExtractRequest{}, "A text to extract entities from. `entity_types` restricts the entities returned to these tags.")
+	d.Enum(ner.ExtractRequest{}, "format", formatText, formatHTML, formatSocial)
+	d.Describe(ner.ExtractResponse{}, "Entities of HTML input, with the visible text their offsets refer to and the annotated HTML.")
+	d.Describe(ner.BatchDocument{}, "A document of a batch or stream. The id is chosen by the client and echoed in its result.")
+	d.Enum(ner.ExtractOptions{}, "format", formatText, formatHTML, formatSocial)
+	d.Describe(ner.BatchResult{}, "The entities of a document, or the reason it failed.")
+	d.Enum(ner.BatchResult{}, "cache", string(ner.CacheHit), string(ner.CacheMiss))
+	d.Describe(errorResponse{}, "An error, with a message and, for most errors, a machine-readable code.")
+	d.Enum(healthResponse{}, "status", "healthy", "loading", "draining")
+	d.Enum(readinessResponse{}, "status", "ready", "not_ready")
+	d.Enum(readinessResponse{}, "reason", "loading", "reloading", "draining")
+	d.Enum(deepHealthResponse{}, "status", ner.CheckOK, ner.CheckFailed)
+	d.Enum(ner.ComponentCheck{}, "status", ner.CheckOK, ner.CheckFailed)
+}
+
+func formatSchema() *openapi.Schema {
+	return &openapi.Schema{Type: "string", Enum: []any{formatText, formatHTML, formatSocial}}
+}
+
+// formSchema describes the form fields of /ner, with a file upload for
+// multipart forms.
+func formSchema(multipart bool) *openapi.Schema {
+	schema := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+		"text":   {Type: "string", Description: "The text"},
+		"format": formatSchema(),
+	}}
+	if multipart {
+		schema.Properties["file"] = &openapi.Schema{Type: "string", ContentMediaType: "application/octet-stream",
+			Description: "A document to extract the text of, instead of text"}
+	}
+	return schema
+}
+
+func jsonContent(schema *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"application/json": {Schema: schema}}
+}
+
+func cacheHeaders() map[string]openapi.Header {
+	return map[string]openapi.Header{
+		"X-Cache": {Description: "Whether the result came from the result cache, when it is enabled",
+			Schema: &openapi.Schema{Type: "string", Enum: []any{"HIT", "MISS"}}},
+	}
+}
+
+// withErrors adds error responses for statuses to responses.
+func withErrors(d *openapi.Document, responses map[string]*openapi.Response, statuses ...int) map[string]*openapi.Response {
+	for _, status := range statuses {
+		response := &openapi.Response{Description: errorDescriptions[status], Content: jsonContent(d.SchemaOf(errorResponse{}))}
+		if status == http.StatusTooManyRequests {
+			response.Headers = map[string]openapi.Header{
+				"Retry-After": {Description: "Seconds to wait before retrying", Schema: &openapi.Schema{Type: "integer"}},
+			}
+		}
+		responses[fmt.Sprint(status)] = response
+	}
+	return responses
+}
+
+// handleOpenAPI serves the API description, encoded once.
+func handleOpenAPI(d *openapi.Document) gin.HandlerFunc {
+	body, err := json.Marshal(d)
+	if err != nil {
+		panic(fmt.Sprintf("encoding the OpenAPI document: %v", err))
+	}
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
+
+func handleExplorer(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", explorerPage)
+}
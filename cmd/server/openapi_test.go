@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/config"
+	"ner-service-go/internal/metrics"
+	"ner-service-go/internal/ner"
+	"ner-service-go/internal/openapi"
+	"ner-service-go/internal/perceptron"
+	"ner-service-go/internal/testutil"
+)
+
+const testAdminToken = "admin-secret"
+
+// newTestRouter returns the full router, serving a small perceptron model,
+// with the admin endpoints enabled.
+func newTestRouter(t *testing.T) (*gin.Engine, *config.Config) {
+	t.Helper()
+	s := newTestServer(t, nil, nil)
+	return s.router, s.cfg
+}
+
+// testServer is a router built by newTestServer, with the configuration,
+// service and state behind it.
+type testServer struct {
+	router  *gin.Engine
+	cfg     *config.Config
+	service *ner.Service
+	state   *serverState
+}
+
+// newTestServer is newTestRouter with the configuration changed by
+// configure and the service reporting extractions to observer, when set.
+func newTestServer(t *testing.T, configure func(*config.Config), observer ner.Observer) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	sentences, err := perceptron.ReadCoNLL(strings.NewReader(testutil.SpanishTrainingData))
+	if err != nil {
+		t.Fatalf("Failed to read training data: %v", err)
+	}
+	model, err := perceptron.Train(sentences, perceptron.TrainOptions{Iterations: 10})
+	if err != nil {
+		t.Fatalf("Failed to train model: %v", err)
+	}
+	modelPath := filepath.Join(t.TempDir(), "perceptron.model")
+	if err := model.SaveFile(modelPath); err != nil {
+		t.Fatalf("Failed to save model: %v", err)
+	}
+
+	cfg := config.Load()
+	cfg.Backend = ner.BackendPerceptron
+	cfg.PerceptronModelPath = modelPath
+	cfg.AdminToken = testAdminToken
+	if configure != nil {
+		configure(cfg)
+	}
+
+	opts := ner.OptionsFromConfig(cfg)
+	opts.Observer = observer
+	nerService, err := ner.NewService(opts)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	t.Cleanup(nerService.Close)
+
+	state := &serverState{}
+	state.service.Store(nerService)
+	state.phase.Store(phaseReady)
+	return &testServer{
+		router:  newRouter(cfg, nerService, nil, state, metrics.New()),
+		cfg:     cfg,
+		service: nerService,
+		state:   state,
+	}
+}
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	r, cfg := newTestRouter(t)
+	doc := apiDocument(cfg)
+
+	var routes, documented []string
+	for _, route := range r.Routes() {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+	for path, item := range doc.Paths {
+		for method := range item {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(routes)
+	sort.Strings(documented)
+
+	if strings.Join(routes, "\n") != strings.Join(documented, "\n") {
+		t.Errorf("Expected the documented operations to match the routes\nroutes:\n%s\n\ndocumented:\n%s",
+			strings.Join(routes, "\n"), strings.Join(documented, "\n"))
+	}
+}
+
+// multipartBody encodes fields as a form, with content as an uploaded file
+// when filename is set.
+func multipartBody(t *testing.T, fields map[string]string, filename, content string) (string, string) {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		w.WriteField(name, value)
+	}
+	if filename != "" {
+		part, err := w.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		part.Write([]byte(content))
+	}
+	w.Close()
+	return body.String(), w.FormDataContentType()
+}
+
+// TestOpenAPI_ResponsesMatchDocument sends the documented examples and
+// requests that fail in the documented ways, and checks that every response
+// has a documented status and content type, and a body that validates
+// against the documented schema.
+func TestOpenAPI_ResponsesMatchDocument(t *testing.T) {
+	r, cfg := newTestRouter(t)
+	doc := apiDocument(cfg)
+
+	example := func(method, path, contentType string) string {
+		t.Helper()
+		media := doc.Operation(method, path).RequestBody.Content[contentType]
+		if s, ok := media.Example.(string); ok {
+			return s
+		}
+		b, err := json.Marshal(media.Example)
+		if err != nil {
+			t.Fatalf("Failed to encode the %s example of %s %s: %v", contentType, method, path, err)
+		}
+		return string(b)
+	}
+	formBody, formType := multipartBody(t, map[string]string{"text": "María García vive en Madrid."}, "", "")
+	uploadBody, uploadType := multipartBody(t, nil, "noticia.txt", "Pedro Sánchez visitó Barcelona.")
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		header      http.Header
+		body        string
+		status      int
+	}{
+		{name: "health", method: http.MethodGet, target: "/health", status: http.StatusOK},
+		{name: "liveness", method: http.MethodGet, target: "/livez", status: http.StatusOK},
+		{name: "readiness", method: http.MethodGet, target: "/readyz", status: http.StatusOK},
+		{name: "deep health", method: http.MethodGet, target: "/healthz/deep", status: http.StatusOK},
+		{name: "version", method: http.MethodGet, target: "/version", status: http.StatusOK},
+		{name: "stats", method: http.MethodGet, target: "/stats", status: http.StatusOK},
+		{name: "metrics", method: http.MethodGet, target: "/metrics", status: http.StatusOK},
+		{name: "openapi", method: http.MethodGet, target: "/openapi.json", status: http.StatusOK},
+		{name: "explorer", method: http.MethodGet, target: "/docs", status: http.StatusOK},
+
+		{name: "json", method: http.MethodPost, target: "/ner", contentType: "application/json",
+			body: example(http.MethodPost, "/ner", "application/json"), status: http.StatusOK},
+		{name: "plain text", method: http.MethodPost, target: "/ner", contentType: "text/plain",
+			body: example(http.MethodPost, "/ner", "text/plain"), status: http.StatusOK},
+		{name: "html", method: http.MethodPost, target: "/ner", contentType: "text/html",
+			body: example(http.MethodPost, "/ner", "text/html"), status: http.StatusOK},
+		{name: "annotated html", method: http.MethodPost, target: "/ner?annotate=true", contentType: "text/html",
+			body: example(http.MethodPost, "/ner", "text/html"), status: http.StatusOK},
+		{name: "html without text", method: http.MethodPost, target: "/ner?annotate=true", contentType: "text/html",
+			body: "<p></p>", status: http.StatusOK},
+		{name: "social", method: http.MethodPost, target: "/ner?format=social&entity_types=PERSON", contentType: "text/plain",
+			body: "Hoy con @pedro en #Madrid", status: http.StatusOK},
+		{name: "url-encoded form", method: http.MethodPost, target: "/ner", contentType: "application/x-www-form-urlencoded",
+			body: url.Values{"text": {"María García vive en Madrid."}}.Encode(), status: http.StatusOK},
+		{name: "multipart form", method: http.MethodPost, target: "/ner", contentType: formType,
+			body: formBody, status: http.StatusOK},
+		{name: "upload", method: http.MethodPost, target: "/ner", contentType: uploadType,
+			body: uploadBody, status: http.StatusOK},
+		{name: "invalid json", method: http.MethodPost, target: "/ner", contentType: "application/json",
+			body: "{", status: http.StatusBadRequest},
+		{name: "missing text", method: http.MethodPost, target: "/ner", contentType: "application/json",
+			body: "{}", status: http.StatusBadRequest},
+		{name: "unsupported format", method: http.MethodPost, target: "/ner?format=xml", contentType: "text/plain",
+			body: "María García", status: http.StatusBadRequest},
+		{name: "invalid timeout", method: http.MethodPost, target: "/ner", contentType: "text/plain",
+			header: http.Header{timeoutHeader: {"soon"}}, body: "María García", status: http.StatusBadRequest},
+		{name: "undecodable charset", method: http.MethodPost, target: "/ner?charset=klingon", contentType: "text/plain",
+			body: "María García", status: http.StatusBadRequest},
+
+		{name: "batch", method: http.MethodPost, target: "/ner/batch", contentType: "application/json",
+			body: example(http.MethodPost, "/ner/batch", "application/json"), status: http.StatusOK},
+		{name: "batch document errors", method: http.MethodPost, target: "/ner/batch", contentType: "application/json",
+			body: `{"documents":[{"id":"1","text":""},{"id":"2","text":"Madrid","options":{"format":"xml"}}]}`, status: http.StatusOK},
+		{name: "empty batch", method: http.MethodPost, target: "/ner/batch", contentType: "application/json",
+			body: `{"documents":[]}`, status: http.StatusBadRequest},
+		{name: "oversized batch", method: http.MethodPost, target: "/ner/batch", contentType: "application/json",
+			body:   `{"documents":[` + strings.Repeat(`{"id":"x","text":"a"},`, cfg.BatchMaxDocuments) + `{"id":"y","text":"a"}]}`,
+			status: http.StatusRequestEntityTooLarge},
+
+		{name: "stream", method: http.MethodPost, target: "/ner/stream", contentType: "application/x-ndjson",
+			body: example(http.MethodPost, "/ner/stream", "application/x-ndjson"), status: http.StatusOK},
+		{name: "stream line errors", method: http.MethodPost, target: "/ner/stream?order=completed", contentType: "application/x-ndjson",
+			body: "{\n{\"id\":\"1\",\"text\":\"\"}\n" + strings.Repeat("a", cfg.StreamMaxLineBytes+1) + "\n", status: http.StatusOK},
+		{name: "stream order", method: http.MethodPost, target: "/ner/stream?order=random", contentType: "application/x-ndjson",
+			body: "", status: http.StatusBadRequest},
+		{name: "stream content type", method: http.MethodPost, target: "/ner/stream", contentType: "application/json",
+			body: "{}", status: http.StatusUnsupportedMediaType},
+
+		{name: "reload without token", method: http.MethodPost, target: "/admin/reload", status: http.StatusUnauthorized},
+		{name: "reload", method: http.MethodPost, target: "/admin/reload",
+			header: http.Header{"Authorization": {"Bearer " + testAdminToken}}, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			checkResponse(t, doc, tt.method, req.URL.Path, w)
+		})
+	}
+}
+
+// checkResponse fails the test when the response is not documented for the
+// operation, or its body does not match the documented schema.
+func checkResponse(t *testing.T, doc *openapi.Document, method, path string, w *httptest.ResponseRecorder) {
+	t.Helper()
+
+	op := doc.Operation(method, path)
+	if op == nil {
+		t.Fatalf("Expected %s %s to be documented", method, path)
+	}
+	response, ok := op.Responses[fmt.Sprint(w.Code)]
+	if !ok {
+		t.Fatalf("Expected status %d of %s %s to be documented", w.Code, method, path)
+	}
+	// Header values are strings, so only string schemas can check them
+	for name, header := range response.Headers {
+		if value := w.Header().Get(name); value != "" && header.Schema.Type == "string" {
+			if err := doc.Validate(header.Schema, value); err != nil {
+				t.Errorf("Expected the %s header to match the document, but got %q: %v", name, value, err)
+			}
+		}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Failed to parse the response content type %q: %v", w.Header().Get("Content-Type"), err)
+	}
+	media, ok := response.Content[mediaType]
+	if !ok {
+		t.Fatalf("Expected content type %s of status %d to be documented", mediaType, w.Code)
+	}
+
+	switch mediaType {
+	case "application/json":
+		validateJSON(t, doc, media.Schema, w.Body.Bytes())
+	case "application/x-ndjson":
+		lines := 0
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			validateJSON(t, doc, media.Schema, scanner.Bytes())
+			lines++
+		}
+		if lines == 0 {
+			t.Errorf("Expected NDJSON lines, but got none")
+		}
+	default:
+		if err := doc.Validate(media.Schema, w.Body.String()); err != nil {
+			t.Errorf("Expected the body to match the document: %v", err)
+		}
+	}
+}
+
+func validateJSON(t *testing.T, doc *openapi.Document, schema *openapi.Schema, body []byte) {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("Failed to decode the body %s: %v", body, err)
+	}
+	if err := doc.Validate(schema, value); err != nil {
+		t.Errorf("Expected the body to match the document, but got %s: %v", body, err)
+	}
+}
+
+func TestOpenAPI_Document(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	var doc struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode the document: %v", err)
+	}
+	if doc.OpenAPI != openapi.Version {
+		t.Errorf("Expected OpenAPI version %s, but got %s", openapi.Version, doc.OpenAPI)
+	}
+	for _, name := range []string{"ExtractRequest", "Entity", "ErrorResponse"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("Expected a %s schema", name)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if !strings.Contains(w.Body.String(), "openapi.json") {
+		t.Errorf("Expected the explorer to load openapi.json")
+	}
+	if strings.Contains(w.Body.String(), "https://") {
+		t.Errorf("Expected the explorer to load nothing from the internet")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/ner"
+)
+
+// reloadModel loads the model file again and swaps it in, logging the
+// outcome.
+func reloadModel(ctx context.Context, nerService *ner.Service, trigger string) (ner.ModelInfo, error) {
+	slog.Info("Reloading model", "trigger", trigger)
+	info, err := nerService.Reload(ctx)
+	if err != nil {
+		slog.Error("Model reload failed, keeping the previous model", "error", err)
+		return info, err
+	}
+	slog.Info("Loaded model", "generation", info.Generation, "path", info.Path, "load_seconds", info.LoadSeconds)
+	return info, nil
+}
+
+// reloadOnSignal reloads the model on SIGHUP until ctx ends.
+func reloadOnSignal(ctx context.Context, nerService *ner.Service) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	for {
+		select {
+		case <-hangups:
+			reloadModel(ctx, nerService, "SIGHUP")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchModel reloads the model when its file changes, until ctx ends. A
+// change is only acted on once the file stayed the same for a whole
+// interval, so a model still being copied into place is not loaded.
+func watchModel(ctx context.Context, nerService *ner.Service, path string, interval time.Duration) {
+	loaded, _ := os.Stat(path)
+	var pending os.FileInfo
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		info, err := os.Stat(path)
+		if err != nil || sameFile(info, loaded) {
+			pending = nil
+			continue
+		}
+		if !sameFile(info, pending) {
+			pending = info
+			continue
+		}
+		// Try each version of the file once, even when it fails to load
+		loaded, pending = info, nil
+		reloadModel(ctx, nerService, "model file changed")
+	}
+}
+
+func sameFile(a, b os.FileInfo) bool {
+	return a != nil && b != nil && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// requireAdmin authorizes requests bearing the admin token.
+func requireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(requestToken(c)), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Admin token required", "code": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleReload reloads the model and reports the model now serving
+// requests. It answers once the new model is in use, or with 422 when it
+// was rejected and the previous model kept.
+func handleReload(nerService *ner.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info, err := reloadModel(context.Background(), nerService, "admin request")
+		if errors.Is(err, ner.ErrReloadInProgress) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A model reload is already in progress", "code": "reload_in_progress"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Model reload failed: " + err.Error(), "code": "reload_failed"})
+			return
+		}
+		c.JSON(http.StatusOK, info)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/ner"
+	"ner-service-go/internal/pool"
+)
+
+// shedLoad admits a request at the given priority, or turns it away with 429
+// and a Retry-After estimate when more than maxDepth requests are already
+// queued ahead of it or its estimated wait exceeds maxWait. Zero disables
+// either limit. Interactive requests are only queued behind other
+// interactive ones, so they keep being admitted while batch traffic is shed.
+func shedLoad(nerService *ner.Service, priority pool.Priority, maxDepth int, maxWait time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats := nerService.PoolStats()
+		ahead := stats.QueueDepthHigh
+		if priority == pool.PriorityLow {
+			ahead = stats.QueueDepth
+		}
+		wait := nerService.EstimatedWait(priority)
+
+		if (maxDepth > 0 && ahead >= maxDepth) || (maxWait > 0 && wait > maxWait) {
+			retryAfter := max(int(math.Ceil(wait.Seconds())), 1)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "Server is overloaded, retry after the Retry-After delay",
+				"code":  "overloaded",
+			})
+			return
+		}
+
+		c.Request = c.Request.WithContext(pool.WithPriority(c.Request.Context(), priority))
+		c.Next()
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"ner-service-go/internal/config"
+	"ner-service-go/internal/pool"
+)
+
+// TestShedLoad fills the queue of a single extractor with a batch request,
+// and checks that the next batch request is turned away while an
+// interactive one is admitted and served first.
+func TestShedLoad(t *testing.T) {
+	observer := newGateObserver()
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.PoolSize = 1
+		cfg.ShedQueueDepth = 1
+		cfg.ShedMaxWait = 0
+	}, observer)
+
+	post := func(target, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+	waitQueued := func(depth int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for s.service.PoolStats().QueueDepth != depth {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d queued requests, but got %d", depth, s.service.PoolStats().QueueDepth)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	const (
+		runningText     = "María García vive en Madrid."
+		batchText       = "Pedro Sánchez visitó Barcelona."
+		interactiveText = "Trabajo en Sevilla."
+	)
+	done := make(chan *httptest.ResponseRecorder, 3)
+	go func() { done <- post("/ner", "text/plain", runningText) }()
+	observer.waitStarted(t)
+	go func() { done <- post("/ner/batch", "application/json", `{"documents":[{"id":"1","text":"`+batchText+`"}]}`) }()
+	waitQueued(1)
+
+	expectedRetry := max(int(math.Ceil(s.service.EstimatedWait(pool.PriorityLow).Seconds())), 1)
+	w := post("/ner/batch", "application/json", `{"documents":[{"id":"2","text":"Madrid"}]}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, but got %d: %s", w.Code, w.Body.String())
+	}
+	if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retry != expectedRetry {
+		t.Errorf("Expected Retry-After %d, but got %q", expectedRetry, w.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(w.Body.String(), `"code":"overloaded"`) {
+		t.Errorf("Expected the overloaded code, but got %s", w.Body.String())
+	}
+
+	// Interactive requests only queue behind other interactive ones
+	go func() { done <- post("/ner", "text/plain", interactiveText) }()
+	waitQueued(2)
+
+	close(observer.release)
+	for _, expected := range []string{interactiveText, batchText} {
+		if e := observer.waitStarted(t); e.Characters != utf8.RuneCountInString(expected) {
+			t.Errorf("Expected %q to be extracted next, but got an extraction of %d characters", expected, e.Characters)
+		}
+	}
+	for range 3 {
+		if w := <-done; w.Code != http.StatusOK {
+			t.Errorf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serveUntilSignal serves until the server fails or SIGTERM or SIGINT
+// arrives. It then moves the server to the draining phase, stops accepting
+// connections and waits up to gracePeriod for in-flight and queued requests
+// to finish. Requests still running after that are canceled. A second
+// signal stops the process right away.
+func serveUntilSignal(server *http.Server, useTLS bool, state *serverState, gracePeriod time.Duration) error {
+	// Request contexts derive from base, so canceling it stops requests
+	// still waiting for an extractor when the grace period is over
+	base, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server.BaseContext = func(net.Listener) context.Context { return base }
+
+	signals, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		if useTLS {
+			served <- server.ListenAndServeTLS("", "")
+		} else {
+			served <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-signals.Done():
+	}
+	stop()
+
+	slog.Info("Shutting down, draining requests", "grace_period", gracePeriod.String())
+	state.phase.Store(phaseDraining)
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("Grace period over, canceling remaining requests")
+		cancelRequests()
+		server.Close()
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
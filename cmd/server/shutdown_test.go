@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"ner-service-go/internal/ner"
+)
+
+// TestServeUntilSignal_Drain sends SIGTERM while a request holds the only
+// extractor, and checks that new connections are refused while the request
+// completes, and that the server only returns, letting main free the
+// extractors, once it has.
+func TestServeUntilSignal_Drain(t *testing.T) {
+	observer := newGateObserver()
+	s := newTestServer(t, nil, observer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	server := &http.Server{Addr: addr, Handler: s.router}
+	served := make(chan error, 1)
+	go func() { served <- serveUntilSignal(server, false, s.state, 10*time.Second) }()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := client.Get("http://" + addr + "/livez")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	type response struct {
+		status   int
+		entities []ner.Entity
+		err      error
+	}
+	inflight := make(chan response, 1)
+	go func() {
+		resp, err := client.Post("http://"+addr+"/ner", "text/plain", strings.NewReader("María García vive en Madrid."))
+		if err != nil {
+			inflight <- response{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		var r response
+		r.status = resp.StatusCode
+		r.err = json.NewDecoder(resp.Body).Decode(&r.entities)
+		inflight <- r
+	}()
+	observer.waitStarted(t)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("Failed to send SIGTERM: %v", err)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for s.state.phase.Load() != phaseDraining {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the server to start draining")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// New connections are refused once the listener is closed
+	refused := false
+	for time.Now().Before(deadline) {
+		resp, err := client.Get("http://" + addr + "/livez")
+		if err != nil {
+			refused = true
+			break
+		}
+		resp.Body.Close()
+		time.Sleep(time.Millisecond)
+	}
+	if !refused {
+		t.Errorf("Expected new requests to be refused while draining")
+	}
+
+	select {
+	case err := <-served:
+		t.Fatalf("Expected the server to wait for the in-flight request, but it returned %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if busy := s.service.PoolStats().Busy; busy != 1 {
+		t.Errorf("Expected the extractor to still be in use, but %d are busy", busy)
+	}
+
+	close(observer.release)
+	r := <-inflight
+	if r.err != nil || r.status != http.StatusOK || len(r.entities) == 0 {
+		t.Errorf("Expected the in-flight request to complete with entities, but got status %d, %v, %v", r.status, r.entities, r.err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown, but got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the server to return once drained")
+	}
+
+	// As in main, the extractors are freed after the drain, which no
+	// request is waiting on anymore
+	closed := make(chan struct{})
+	go func() {
+		s.service.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the extractors to be freed right after the drain")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/ner"
+)
+
+// Result orders accepted by /ner/stream.
+const (
+	streamOrderInput     = "input"
+	streamOrderCompleted = "completed"
+)
+
+// streamJob is a document read from the stream, or the reason its line could
+// not be parsed.
+type streamJob struct {
+	seq int
+	doc ner.BatchDocument
+	err string
+}
+
+type streamResult struct {
+	seq    int
+	result ner.BatchResult
+}
+
+// handleStream extracts entities from an NDJSON stream of documents, one
+// {"id", "text", "options"} object per line, and writes one result line per
+// document while the request is still being read. At most twice as many
+// documents as there are extractors are held in memory; the body is not read
+// further until results have been written, so a slow client slows down its
+// own stream. Results follow the input order unless ?order=completed is set.
+// The request timeout applies to each document, not to the whole stream.
+func handleStream(nerService *ner.Service, maxLineBytes int, serverTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isContentType(c.GetHeader("Content-Type"), "application/x-ndjson") {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Expected an application/x-ndjson request body"})
+			return
+		}
+		timeout, err := requestTimeout(c, serverTimeout)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, invalidTimeoutResponse)
+			return
+		}
+		order := c.DefaultQuery("order", streamOrderInput)
+		if order != streamOrderInput && order != streamOrderCompleted {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported order, expected input or completed"})
+			return
+		}
+
+		// HTTP/1.1 servers stop reading the body once the response starts
+		// unless full duplex is enabled
+		if err := http.NewResponseController(c.Writer).EnableFullDuplex(); err != nil {
+			loggerFrom(c.Request.Context()).Warn("Full-duplex streaming unavailable", "error", err)
+		}
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+
+		workers := nerService.PoolStats().Size
+		window := make(chan struct{}, 2*workers)
+		jobs := make(chan streamJob)
+		results := make(chan streamResult)
+		readErr := make(chan error, 1)
+
+		go func() {
+			readErr <- readStream(ctx, c.Request.Body, maxLineBytes, window, jobs)
+			close(jobs)
+		}()
+
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := range jobs {
+					result := ner.BatchResult{ID: job.doc.ID, Error: job.err}
+					if job.err == "" {
+						result = extractStreamDocument(ctx, nerService, job.doc, timeout)
+					}
+					select {
+					case results <- streamResult{seq: job.seq, result: result}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		encoder := json.NewEncoder(c.Writer)
+		write := func(line any) bool {
+			if err := encoder.Encode(line); err != nil {
+				cancel()
+				return false
+			}
+			c.Writer.Flush()
+			return true
+		}
+
+		pending := make(map[int]ner.BatchResult)
+		next := 0
+		for r := range results {
+			if order == streamOrderCompleted {
+				if write(r.result) {
+					<-window
+				}
+				continue
+			}
+			pending[r.seq] = r.result
+			for result, ok := pending[next]; ok; result, ok = pending[next] {
+				delete(pending, next)
+				next++
+				if write(result) {
+					<-window
+				}
+			}
+		}
+
+		err = <-readErr
+		switch {
+		case err == nil || ctx.Err() != nil:
+		case errors.Is(err, bufio.ErrTooLong):
+			write(gin.H{"error": fmt.Sprintf("Line exceeds %d bytes, stream stopped", maxLineBytes)})
+		default:
+			write(gin.H{"error": "Failed to read request body, stream stopped"})
+		}
+	}
+}
+
+// extractStreamDocument extracts one document of a stream within timeout,
+// or without a deadline when timeout is zero.
+func extractStreamDocument(ctx context.Context, nerService *ner.Service, doc ner.BatchDocument, timeout time.Duration) ner.BatchResult {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return extractBatchDocument(ctx, nerService, doc)
+}
+
+// readStream parses one document per line and queues it, waiting for a
+// free slot in window first. Documents without an id get their line number.
+func readStream(ctx context.Context, body io.Reader, maxLineBytes int, window chan<- struct{}, jobs chan<- streamJob) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, min(64*1024, maxLineBytes)), maxLineBytes)
+
+	seq, line := 0, 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		job := streamJob{seq: seq}
+		if err := json.Unmarshal(data, &job.doc); err != nil {
+			job.err = fmt.Sprintf("Invalid JSON on line %d", line)
+		}
+		if job.doc.ID == "" {
+			job.doc.ID = strconv.Itoa(line)
+		}
+
+		select {
+		case window <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		seq++
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ner-service-go/internal/ner"
+)
+
+func decodeStream(t *testing.T, body io.Reader) []ner.BatchResult {
+	t.Helper()
+	var results []ner.BatchResult
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		var result ner.BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("Failed to decode line %s: %v", scanner.Text(), err)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestHandleStream_OneResultPerLine(t *testing.T) {
+	s := newTestServer(t, nil, nil)
+
+	body := `{"id":"a","text":"María García vive en Madrid."}
+{"id":"b","text":"Pedro Sánchez visitó Barcelona."}
+
+{"text":"Trabajo en Sevilla."}
+`
+	req := httptest.NewRequest(http.MethodPost, "/ner/stream", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	results := decodeStream(t, w.Body)
+	// Blank lines are skipped, and documents without an id get their line
+	// number
+	expected := []string{"a", "b", "4"}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, but got %d: %+v", len(expected), len(results), results)
+	}
+	for i, result := range results {
+		if result.ID != expected[i] {
+			t.Errorf("Expected result %d to be %s, but got %s", i, expected[i], result.ID)
+		}
+		if result.Error != "" || result.ExtractResponse == nil || len(result.Entities) == 0 {
+			t.Errorf("Expected entities for %s, but got %+v", result.ID, result)
+		}
+	}
+}
+
+func TestHandleStream_MalformedLine(t *testing.T) {
+	s := newTestServer(t, nil, nil)
+
+	body := `{"id":"a","text":"María García vive en Madrid."}
+{"id":"b","text":
+{"id":"c","text":"Pedro Sánchez visitó Barcelona."}
+`
+	req := httptest.NewRequest(http.MethodPost, "/ner/stream", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	results := decodeStream(t, w.Body)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, but got %d: %+v", len(results), results)
+	}
+	if results[1].ID != "2" || results[1].Error != "Invalid JSON on line 2" {
+		t.Errorf("Expected an error for line 2, but got %+v", results[1])
+	}
+	if results[2].ID != "c" || results[2].Error != "" || len(results[2].Entities) == 0 {
+		t.Errorf("Expected the stream to go on after the malformed line, but got %+v", results[2])
+	}
+}
+
+// A client going away ends the stream while its body is still open.
+func TestHandleStream_ClientDisconnect(t *testing.T) {
+	s := newTestServer(t, nil, nil)
+	handled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(handled)
+		s.router.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	body, writer := io.Pipe()
+	defer writer.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/ner/stream", body)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	go writer.Write([]byte(`{"id":"a","text":"María García vive en Madrid."}` + "\n"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var first ner.BatchResult
+	line, err := bufio.NewReader(resp.Body).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("Failed to read the first result: %v", err)
+	}
+	if err := json.Unmarshal(line, &first); err != nil || first.ID != "a" {
+		t.Fatalf("Expected the result of a, but got %s: %v", line, err)
+	}
+
+	cancel()
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the stream to stop after the client disconnected")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"ner-service-go/internal/config"
+	"ner-service-go/internal/tlsconfig"
+)
+
+// certificateCheckInterval is how often the certificate, key and client CA
+// files are checked for changes.
+const certificateCheckInterval = 5 * time.Second
+
+// tlsOptions returns the TLS settings of cfg.
+func tlsOptions(cfg *config.Config) tlsconfig.Options {
+	return tlsconfig.Options{
+		CertFile:     cfg.TLSCertFile,
+		KeyFile:      cfg.TLSKeyFile,
+		MinVersion:   cfg.TLSMinVersion,
+		CipherSuites: cfg.TLSCipherSuites,
+		ClientCAFile: cfg.TLSClientCAFile,
+		ClientAuth:   cfg.TLSClientAuth,
+	}
+}
+
+// watchCertificates reloads renewed certificates periodically, so they are
+// served without a restart.
+func watchCertificates(certificates *tlsconfig.Reloader) {
+	for range time.Tick(certificateCheckInterval) {
+		changed, err := certificates.Reload()
+		if err != nil {
+			slog.Error("Error reloading TLS certificate, keeping the previous one", "error", err)
+		} else if changed {
+			slog.Info("Reloaded TLS certificate")
+		}
+	}
+}
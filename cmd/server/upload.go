@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+
+	"ner-service-go/internal/docextract"
+	"ner-service-go/internal/ner"
+)
+
+// maxUploadSize is the largest document accepted in a file upload.
+const maxUploadSize = 50 << 20
+
+var errUploadTooLarge = errors.New("uploaded file is too large")
+
+// extractUpload extracts entities from an uploaded DOCX, ODT, PDF, RTF, EPUB,
+// HTML or plain text file. Entities carry the page or paragraph they were
+// found in when the format provides it. declaredCharset overrides encoding
+// detection for plain text and HTML files, and types restricts the entities
+// returned.
+func extractUpload(ctx context.Context, nerService *ner.Service, header *multipart.FileHeader, declaredCharset string, types []string) ([]ner.Entity, error) {
+	if header.Size > maxUploadSize {
+		return nil, errUploadTooLarge
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if len(data) > maxUploadSize {
+		return nil, errUploadTooLarge
+	}
+
+	types, err = entityTypes(ctx, types)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := docextract.Extract(header.Filename, data, declaredCharset)
+	if err != nil {
+		return nil, err
+	}
+	if err := chargeQuota(ctx, doc.Text); err != nil {
+		return nil, err
+	}
+
+	logInput(ctx, doc.Text)
+	entities, err := nerService.ExtractDocument(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	entities = filterEntities(entities, types)
+	logEntities(ctx, entities)
+	return entities, nil
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// reloadInterval is how often the keys file is checked for changes.
+const reloadInterval = time.Second
+
+// Principal is an authenticated client.
+type Principal struct {
+	// ID identifies the client in rate limits and usage counters, such as
+	// "key:1a2b3c4d".
+	ID   string
+	Name string
+	Limits
+	// Models and EntityTypes restrict the models and entity types the
+	// client may use. Empty lists allow all of them.
+	Models      []string
+	EntityTypes []string
+	// CertSubject is the subject of the client's verified TLS certificate,
+	// when it sent one.
+	CertSubject string
+
+	usage *Usage
+}
+
+// AllowsModel reports whether the principal may use the named model.
+func (p *Principal) AllowsModel(name string) bool {
+	return allowsAll(p.Models) || slices.Contains(p.Models, name)
+}
+
+// AllowsEntityType reports whether the principal may receive entities with
+// the given tag.
+func (p *Principal) AllowsEntityType(tag string) bool {
+	return allowsAll(p.EntityTypes) || slices.Contains(p.EntityTypes, tag)
+}
+
+// Charge counts chars extracted for the principal against its character
+// quotas, failing with ErrQuotaExceeded when they would be exceeded.
+func (p *Principal) Charge(chars int) error {
+	return p.usage.Charge(p.ID, int64(chars), p.Limits, time.Now())
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated client.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the authenticated client of a request, or nil.
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Options configure an Authenticator. API keys are accepted when KeysFile
+// is set, and JWT bearer tokens when JWT is.
+type Options struct {
+	KeysFile  string
+	UsageFile string
+	JWT       *JWTOptions
+}
+
+// Authenticator checks API keys against the keys file, picking up keys
+// created or revoked since it was loaded, and JWTs against the signing keys
+// of their issuer, and applies their rate limits.
+type Authenticator struct {
+	keysPath string
+	usage    *Usage
+	jwt      *jwtVerifier
+
+	mu      sync.Mutex
+	keys    *KeyFile
+	modTime time.Time
+	checked time.Time
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// New returns an Authenticator for the given options.
+func New(opts Options) (*Authenticator, error) {
+	usage, err := OpenUsage(opts.UsageFile)
+	if err != nil {
+		return nil, err
+	}
+	a := &Authenticator{
+		keysPath: opts.KeysFile,
+		usage:    usage,
+		keys:     &KeyFile{},
+		buckets:  make(map[string]*bucket),
+		now:      time.Now,
+	}
+	if a.keysPath != "" {
+		if err := a.reload(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.JWT != nil {
+		keys, err := NewKeySet(opts.JWT.JWKS, opts.JWT.JWKSRefresh)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = &jwtVerifier{
+			keys:     keys,
+			issuer:   opts.JWT.Issuer,
+			audience: opts.JWT.Audience,
+			scopes:   opts.JWT.Scopes,
+		}
+	}
+	return a, nil
+}
+
+// Authenticate returns the client holding a bearer token, an API key or a
+// JWT. API keys fail with ErrInvalidKey or ErrRevokedKey, JWTs with
+// ErrInvalidToken, or ErrNoScope when none of their scopes is known.
+func (a *Authenticator) Authenticate(token string) (*Principal, error) {
+	if IsAPIKey(token) || a.jwt == nil {
+		return a.AuthenticateKey(token)
+	}
+	return a.AuthenticateJWT(token)
+}
+
+// AuthenticateJWT returns the client a JWT was issued to, with the models,
+// entity types and limits its scopes allow.
+func (a *Authenticator) AuthenticateJWT(token string) (*Principal, error) {
+	if a.jwt == nil {
+		return nil, errors.New("JWT authentication is not configured")
+	}
+	claims, err := a.jwt.verify(token, a.now())
+	if err != nil {
+		return nil, err
+	}
+	scope, err := a.jwt.scopeFor(claims)
+	if err != nil {
+		return nil, err
+	}
+	p := a.NewPrincipal("jwt:"+claims.Subject, claims.Subject, scope.Limits)
+	p.Models, p.EntityTypes = scope.Models, scope.EntityTypes
+	return p, nil
+}
+
+// AuthenticateKey returns the client holding the API key plaintext.
+func (a *Authenticator) AuthenticateKey(plaintext string) (*Principal, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.keysPath == "" {
+		return nil, ErrInvalidKey
+	}
+	if now := a.now(); now.Sub(a.checked) >= reloadInterval {
+		a.checked = now
+		// Keep serving the keys already loaded if the file is being
+		// rewritten
+		a.reloadIfChanged()
+	}
+	key, err := a.keys.lookup(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return a.NewPrincipal("key:"+key.ID, key.Name, key.Limits), nil
+}
+
+// AuthenticateCert returns the client holding a verified TLS client
+// certificate with the given subject. Such clients are identified by their
+// certificate and have no limits.
+func (a *Authenticator) AuthenticateCert(subject string) *Principal {
+	p := a.NewPrincipal("cert:"+subject, subject, Limits{})
+	p.CertSubject = subject
+	return p
+}
+
+// NewPrincipal returns a client with the given limits whose usage is
+// counted by the Authenticator.
+func (a *Authenticator) NewPrincipal(id, name string, limits Limits) *Principal {
+	return &Principal{ID: id, Name: name, Limits: limits, usage: a.usage}
+}
+
+// Allow takes a request from the principal's token bucket. Principals
+// without a rate limit are always allowed.
+func (a *Authenticator) Allow(p *Principal) RateLimit {
+	if p.RatePerSecond <= 0 {
+		return RateLimit{Allowed: true}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	b := a.buckets[p.ID]
+	if b == nil || b.rate != p.RatePerSecond || int(b.burst) != max(p.Burst, 1) {
+		b = newBucket(p.RatePerSecond, p.Burst, now)
+		a.buckets[p.ID] = b
+	}
+	return b.take(now)
+}
+
+// Usage returns the usage counters.
+func (a *Authenticator) Usage() *Usage {
+	return a.usage
+}
+
+// Flush saves the usage counters.
+func (a *Authenticator) Flush() error {
+	return a.usage.Flush()
+}
+
+func (a *Authenticator) reloadIfChanged() error {
+	info, err := os.Stat(a.keysPath)
+	if err == nil && info.ModTime().Equal(a.modTime) {
+		return nil
+	}
+	return a.reload()
+}
+
+func (a *Authenticator) reload() error {
+	var modTime time.Time
+	if info, err := os.Stat(a.keysPath); err == nil {
+		modTime = info.ModTime()
+	}
+	keys, err := LoadKeyFile(a.keysPath)
+	if err != nil {
+		return err
+	}
+	a.keys, a.modTime = keys, modTime
+	return nil
+}
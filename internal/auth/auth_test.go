@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyFile_CreateAndAuthenticate(t *testing.T) {
+	dir := t.TempDir()
+	keysPath := filepath.Join(dir, "keys.json")
+
+	var file KeyFile
+	key, plaintext, err := file.Create("newsroom", Limits{DailyChars: 1000})
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, "ner_"+key.ID+"_") {
+		t.Errorf("Expected the key to start with ner_%s_, but got %q", key.ID, plaintext)
+	}
+	if err := file.Save(keysPath); err != nil {
+		t.Fatalf("Failed to save keys: %v", err)
+	}
+
+	data, _ := os.ReadFile(keysPath)
+	if strings.Contains(string(data), plaintext) {
+		t.Errorf("Expected the keys file to hold only a hash of the key")
+	}
+
+	a, err := New(Options{KeysFile: keysPath, UsageFile: filepath.Join(dir, "usage.json")})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	principal, err := a.AuthenticateKey(plaintext)
+	if err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	if principal.ID != "key:"+key.ID || principal.Name != "newsroom" || principal.DailyChars != 1000 {
+		t.Errorf("Expected the newsroom key with its limits, but got %+v", principal)
+	}
+
+	for _, bad := range []string{"", "secret", plaintext + "x", "ner_" + key.ID + "_other"} {
+		if _, err := a.AuthenticateKey(bad); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey for %q, but got %v", bad, err)
+		}
+	}
+}
+
+func TestAuthenticator_PicksUpRevocation(t *testing.T) {
+	dir := t.TempDir()
+	keysPath := filepath.Join(dir, "keys.json")
+
+	var file KeyFile
+	key, plaintext, _ := file.Create("newsroom", Limits{})
+	file.Save(keysPath)
+
+	a, err := New(Options{KeysFile: keysPath, UsageFile: filepath.Join(dir, "usage.json")})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	if _, err := a.AuthenticateKey(plaintext); err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+
+	if err := file.Revoke(key.ID); err != nil {
+		t.Fatalf("Failed to revoke: %v", err)
+	}
+	file.Save(keysPath)
+	// Make sure the modification time changes on coarse filesystems
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(keysPath, later, later)
+
+	now = now.Add(2 * reloadInterval)
+	if _, err := a.AuthenticateKey(plaintext); !errors.Is(err, ErrRevokedKey) {
+		t.Errorf("Expected ErrRevokedKey after revocation, but got %v", err)
+	}
+	if err := file.Revoke("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, but got %v", err)
+	}
+}
+
+func TestAuthenticator_RateLimit(t *testing.T) {
+	a, err := New(Options{KeysFile: filepath.Join(t.TempDir(), "keys.json"), UsageFile: filepath.Join(t.TempDir(), "usage.json")})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	principal := a.NewPrincipal("key:test", "test", Limits{RatePerSecond: 2, Burst: 3})
+
+	for i := range 3 {
+		if limit := a.Allow(principal); !limit.Allowed || limit.Remaining != 2-i {
+			t.Errorf("Expected request %d allowed with %d remaining, but got %+v", i+1, 2-i, limit)
+		}
+	}
+	limit := a.Allow(principal)
+	if limit.Allowed || limit.Limit != 3 || limit.RetryAfter != 500*time.Millisecond || limit.Reset != 1500*time.Millisecond {
+		t.Errorf("Expected the 4th request refused, retry after 500ms and full after 1.5s, but got %+v", limit)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if limit := a.Allow(principal); !limit.Allowed {
+		t.Errorf("Expected a request allowed after a token was refilled, but got %+v", limit)
+	}
+
+	if limit := a.Allow(a.NewPrincipal("key:free", "free", Limits{})); !limit.Allowed {
+		t.Errorf("Expected principals without a rate limit to be allowed")
+	}
+}
+
+func TestUsage_Quotas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	u, err := OpenUsage(path)
+	if err != nil {
+		t.Fatalf("Failed to open usage: %v", err)
+	}
+	limits := Limits{DailyChars: 100, MonthlyChars: 150}
+	day := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	if err := u.Charge("key:a", 80, limits, day); err != nil {
+		t.Fatalf("Failed to charge: %v", err)
+	}
+	if err := u.Charge("key:a", 30, limits, day); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected the daily quota to be exceeded, but got %v", err)
+	}
+	if err := u.Charge("key:a", 60, limits, day.Add(time.Hour)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected the daily quota to still apply, but got %v", err)
+	}
+
+	// A new day and month reset both counters
+	if err := u.Charge("key:a", 90, limits, day.Add(24*time.Hour)); err != nil {
+		t.Errorf("Expected the quotas to reset on a new month, but got %v", err)
+	}
+	if err := u.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	reopened, err := OpenUsage(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen usage: %v", err)
+	}
+	if day, month := reopened.Used("key:a", time.Date(2026, 4, 1, 18, 0, 0, 0, time.UTC)); day != 90 || month != 90 {
+		t.Errorf("Expected 90 characters today and this month after reopening, but got %d and %d", day, month)
+	}
+}
+
+func TestAuthenticator_AuthenticateCert(t *testing.T) {
+	a, err := New(Options{UsageFile: filepath.Join(t.TempDir(), "usage.json")})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	principal := a.AuthenticateCert("CN=reporter,O=Newsroom")
+	if principal.ID != "cert:CN=reporter,O=Newsroom" || principal.CertSubject != "CN=reporter,O=Newsroom" {
+		t.Errorf("Expected a principal identified by the certificate subject, but got %+v", principal)
+	}
+	if err := principal.Charge(1000); err != nil {
+		t.Errorf("Expected certificate clients to have no quota, but got %v", err)
+	}
+	if _, err := a.Authenticate("ner_1a2b3c4d_secret"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey without a keys file, but got %v", err)
+	}
+}
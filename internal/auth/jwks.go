@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// minRefreshInterval limits how often an unknown key ID triggers a refresh.
+const minRefreshInterval = time.Minute
+
+// maxJWKSSize is the largest JWKS document accepted.
+const maxJWKSSize = 1 << 20
+
+// jwk is a JSON Web Key, with the fields of RSA and EC public keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// KeySet holds the public keys of a JWKS file or URL, reloading them every
+// refresh interval and when a token names a key it does not know. Reloads
+// run outside the lock, so a slow issuer does not hold up tokens signed with
+// known keys.
+type KeySet struct {
+	source  string
+	refresh time.Duration
+	client  *http.Client
+	// reloads lets concurrent callers share one reload
+	reloads singleflight.Group
+
+	mu      sync.RWMutex
+	keys    map[string]crypto.PublicKey
+	loaded  time.Time
+	modTime time.Time
+	now     func() time.Time
+}
+
+// NewKeySet loads the JWKS at source, a file path or an http(s) URL.
+func NewKeySet(source string, refresh time.Duration) (*KeySet, error) {
+	s := &KeySet{
+		source:  source,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		now:     time.Now,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// key returns the public key with the given ID. Known keys are returned
+// right away, and refreshed in the background when due; an unknown key waits
+// for a reload, at most once per minimum refresh interval. Keys that fail to
+// reload are kept until a reload succeeds.
+func (s *KeySet) key(kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	key, known := s.keys[kid]
+	now := s.now()
+	stale := s.refresh > 0 && now.Sub(s.loaded) >= s.refresh
+	due := stale || (!known && now.Sub(s.loaded) >= minRefreshInterval) || s.fileChanged()
+	s.mu.RUnlock()
+
+	switch {
+	case known && due:
+		s.reload()
+	case !known && due:
+		<-s.reload()
+		s.mu.RLock()
+		key, known = s.keys[kid]
+		s.mu.RUnlock()
+	}
+	if known {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
+
+// reload starts loading the keys, unless a load is already running, and
+// returns a channel that receives its outcome.
+func (s *KeySet) reload() <-chan singleflight.Result {
+	return s.reloads.DoChan("", func() (any, error) {
+		return nil, s.load()
+	})
+}
+
+func (s *KeySet) isURL() bool {
+	return strings.HasPrefix(s.source, "http://") || strings.HasPrefix(s.source, "https://")
+}
+
+func (s *KeySet) fileChanged() bool {
+	if s.isURL() {
+		return false
+	}
+	info, err := os.Stat(s.source)
+	return err == nil && !info.ModTime().Equal(s.modTime)
+}
+
+// load reads and parses the keys without holding the lock, which it only
+// takes to record the attempt and store the result.
+func (s *KeySet) load() error {
+	s.mu.Lock()
+	s.loaded = s.now()
+	s.mu.Unlock()
+
+	var keys map[string]crypto.PublicKey
+	data, modTime, err := s.read()
+	if err == nil {
+		keys, err = parseJWKS(data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modTime = modTime
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS: %w", err)
+	}
+	s.keys = keys
+	return nil
+}
+
+// read returns the JWKS document, and for files their modification time,
+// also when reading them fails.
+func (s *KeySet) read() ([]byte, time.Time, error) {
+	if !s.isURL() {
+		info, err := os.Stat(s.source)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		data, err := os.ReadFile(s.source)
+		return data, info.ModTime(), err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("%s returned %s", s.source, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	return data, time.Time{}, err
+}
+
+// parseJWKS decodes the RSA and P-256 signing keys of a JWKS document,
+// skipping keys of other types.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if errors.Is(err, errUnsupportedKey) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+var errUnsupportedKey = errors.New("unsupported key type")
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.Kty == "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the P-256 curve")
+		}
+		return key, nil
+	default:
+		return nil, errUnsupportedKey
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
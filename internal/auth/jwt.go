@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clockSkew is the leeway allowed when checking token expiry.
+const clockSkew = time.Minute
+
+var (
+	// ErrInvalidToken is returned for bearer tokens that are malformed,
+	// badly signed, expired or issued for another service.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrNoScope is returned for valid tokens whose scopes grant no access.
+	ErrNoScope = errors.New("token has no scope granting access")
+)
+
+// Scope is what a token scope allows: the models and entity types a client
+// may use, and its limits. Empty lists allow everything, as does "*".
+type Scope struct {
+	Models      []string `json:"models,omitempty"`
+	EntityTypes []string `json:"entity_types,omitempty"`
+	Limits
+}
+
+// ScopeFile maps token scopes to what they allow, stored as JSON.
+type ScopeFile struct {
+	Scopes map[string]Scope `json:"scopes"`
+}
+
+// LoadScopeFile reads the scopes file at path.
+func LoadScopeFile(path string) (*ScopeFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scopes file: %w", err)
+	}
+	var file ScopeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse scopes file: %w", err)
+	}
+	return &file, nil
+}
+
+// JWTOptions configure the validation of JWT bearer tokens.
+type JWTOptions struct {
+	// JWKS is the file path or URL of the signing keys, reloaded every
+	// JWKSRefresh.
+	JWKS        string
+	JWKSRefresh time.Duration
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// Scopes maps the scopes of the scope or scp claim to what they allow.
+	Scopes map[string]Scope
+}
+
+// jwtVerifier validates RS256 and ES256 signed tokens against a key set.
+type jwtVerifier struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	scopes   map[string]Scope
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string      `json:"sub"`
+	Issuer    string      `json:"iss"`
+	Audience  stringList  `json:"aud"`
+	ExpiresAt json.Number `json:"exp"`
+	NotBefore json.Number `json:"nbf"`
+	Scope     string      `json:"scope"`
+	Scp       stringList  `json:"scp"`
+}
+
+// stringList decodes a claim that is either a string or a list of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = strings.Fields(s)
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// verify checks the signature and claims of token and returns its claims.
+func (v *jwtVerifier) verify(token string, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	key, err := v.keys.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	exp, err := claims.ExpiresAt.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing exp claim", ErrInvalidToken)
+	}
+	if now.Add(-clockSkew).Unix() >= exp {
+		return nil, fmt.Errorf("%w: token has expired", ErrInvalidToken)
+	}
+	if nbf, err := claims.NotBefore.Int64(); err == nil && now.Add(clockSkew).Unix() < nbf {
+		return nil, fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if v.audience != "" && !slices.Contains(claims.Audience, v.audience) {
+		return nil, fmt.Errorf("%w: token is not issued for %q", ErrInvalidToken, v.audience)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+	return &claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed segment", ErrInvalidToken)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: malformed segment", ErrInvalidToken)
+	}
+	return nil
+}
+
+// verifySignature checks an RS256 or ES256 signature of signed with key.
+// The algorithm must match the type of the key, so that a token cannot pick
+// a weaker check than the key was issued for.
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if ok && rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) == nil {
+			return nil
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		// ES256 signatures are r and s as two 32-byte big-endian integers
+		if ok && len(signature) == 64 {
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			if ecdsa.Verify(ecKey, digest[:], r, s) {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+	}
+	return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+}
+
+// scopeFor combines what the scopes of claims allow: the union of their
+// models and entity types, and the most generous of their limits.
+func (v *jwtVerifier) scopeFor(claims *jwtClaims) (Scope, error) {
+	var combined Scope
+	granted := false
+	allModels, allTypes := false, false
+	for _, name := range append(strings.Fields(claims.Scope), claims.Scp...) {
+		scope, ok := v.scopes[name]
+		if !ok {
+			continue
+		}
+		if !granted {
+			combined.Limits = scope.Limits
+		} else {
+			combined.Limits = mostGenerous(combined.Limits, scope.Limits)
+		}
+		granted = true
+		allModels = allModels || allowsAll(scope.Models)
+		allTypes = allTypes || allowsAll(scope.EntityTypes)
+		combined.Models = append(combined.Models, scope.Models...)
+		combined.EntityTypes = append(combined.EntityTypes, scope.EntityTypes...)
+	}
+	if !granted {
+		return Scope{}, ErrNoScope
+	}
+	if allModels {
+		combined.Models = nil
+	}
+	if allTypes {
+		combined.EntityTypes = nil
+	}
+	return combined, nil
+}
+
+// mostGenerous returns the higher of each limit, zero being unlimited.
+func mostGenerous(a, b Limits) Limits {
+	if a.RatePerSecond == 0 || b.RatePerSecond == 0 {
+		a.RatePerSecond, a.Burst = 0, 0
+	} else {
+		a.RatePerSecond, a.Burst = max(a.RatePerSecond, b.RatePerSecond), max(a.Burst, b.Burst)
+	}
+	if a.DailyChars == 0 || b.DailyChars == 0 {
+		a.DailyChars = 0
+	} else {
+		a.DailyChars = max(a.DailyChars, b.DailyChars)
+	}
+	if a.MonthlyChars == 0 || b.MonthlyChars == 0 {
+		a.MonthlyChars = 0
+	} else {
+		a.MonthlyChars = max(a.MonthlyChars, b.MonthlyChars)
+	}
+	return a
+}
+
+// allowsAll reports whether a list of allowed names allows any name.
+func allowsAll(names []string) bool {
+	return len(names) == 0 || slices.Contains(names, "*")
+}
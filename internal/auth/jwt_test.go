@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testScopes = map[string]Scope{
+	"public":   {EntityTypes: []string{"LOCATION", "ORGANIZATION", "MISC"}, Limits: Limits{DailyChars: 1000}},
+	"internal": {Models: []string{"perceptron"}, Limits: Limits{DailyChars: 5000}},
+}
+
+// signToken returns a JWT with the given claims, signed with key.
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// jwksFor returns a JWKS document with the public keys of keys.
+func jwksFor(keys map[string]crypto.Signer) []byte {
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, key := range keys {
+		switch k := key.Public().(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "RSA", Kid: kid, N: encode(k.N.Bytes()), E: encode(big.NewInt(int64(k.E)).Bytes())})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: encode(k.X.Bytes()), Y: encode(k.Y.Bytes())})
+		}
+	}
+	data, _ := json.Marshal(set)
+	return data
+}
+
+func validClaims(scope string) map[string]any {
+	return map[string]any{
+		"sub":   "reporter",
+		"iss":   "https://issuer.example",
+		"aud":   []string{"ner-service"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": scope,
+	}
+}
+
+func newJWTAuthenticator(t *testing.T, jwks string) *Authenticator {
+	t.Helper()
+	a, err := New(Options{
+		UsageFile: filepath.Join(t.TempDir(), "usage.json"),
+		JWT: &JWTOptions{
+			JWKS:     jwks,
+			Issuer:   "https://issuer.example",
+			Audience: "ner-service",
+			Scopes:   testScopes,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	return a
+}
+
+func TestAuthenticateJWT_RS256AndES256(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	os.WriteFile(jwksPath, jwksFor(map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey}), 0o600)
+	a := newJWTAuthenticator(t, jwksPath)
+
+	for _, tc := range []struct {
+		alg, kid string
+		key      crypto.Signer
+	}{{"RS256", "rsa", rsaKey}, {"ES256", "ec", ecKey}} {
+		principal, err := a.Authenticate(signToken(t, tc.alg, tc.kid, tc.key, validClaims("public")))
+		if err != nil {
+			t.Fatalf("Failed to authenticate %s token: %v", tc.alg, err)
+		}
+		if principal.ID != "jwt:reporter" || principal.DailyChars != 1000 {
+			t.Errorf("Expected the reporter with the public limits, but got %+v", principal)
+		}
+		if principal.AllowsEntityType("PERSON") || !principal.AllowsEntityType("LOCATION") {
+			t.Errorf("Expected the public scope to exclude PERSON entities, but got %v", principal.EntityTypes)
+		}
+	}
+
+	// The algorithm must match the key type
+	if _, err := a.Authenticate(signToken(t, "ES256", "rsa", ecKey, validClaims("public"))); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for an ES256 token naming an RSA key, but got %v", err)
+	}
+}
+
+func TestAuthenticateJWT_Claims(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	os.WriteFile(jwksPath, jwksFor(map[string]crypto.Signer{"k1": key}), 0o600)
+	a := newJWTAuthenticator(t, jwksPath)
+
+	with := func(name string, value any) map[string]any {
+		claims := validClaims("public")
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	for name, token := range map[string]string{
+		"expired":         signToken(t, "ES256", "k1", key, with("exp", time.Now().Add(-time.Hour).Unix())),
+		"missing exp":     signToken(t, "ES256", "k1", key, with("exp", nil)),
+		"not yet valid":   signToken(t, "ES256", "k1", key, with("nbf", time.Now().Add(time.Hour).Unix())),
+		"wrong issuer":    signToken(t, "ES256", "k1", key, with("iss", "https://other.example")),
+		"wrong audience":  signToken(t, "ES256", "k1", key, with("aud", "other-service")),
+		"other signer":    signToken(t, "ES256", "k1", other, validClaims("public")),
+		"unknown key":     signToken(t, "ES256", "k2", key, validClaims("public")),
+		"not a JWT":       "abc.def",
+		"alg none":        "eyJhbGciOiJub25lIiwia2lkIjoiazEifQ.eyJzdWIiOiJ4In0.",
+		"missing subject": signToken(t, "ES256", "k1", key, with("sub", nil)),
+	} {
+		if _, err := a.Authenticate(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Expected ErrInvalidToken for a token that is %s, but got %v", name, err)
+		}
+	}
+
+	if _, err := a.Authenticate(signToken(t, "ES256", "k1", key, validClaims("admin"))); !errors.Is(err, ErrNoScope) {
+		t.Errorf("Expected ErrNoScope for an unknown scope, but got %v", err)
+	}
+
+	// A string audience and scp list are accepted, and scopes combine
+	claims := with("aud", "ner-service")
+	delete(claims, "scope")
+	claims["scp"] = []string{"public", "internal"}
+	principal, err := a.Authenticate(signToken(t, "ES256", "k1", key, claims))
+	if err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	if principal.DailyChars != 5000 || !principal.AllowsEntityType("PERSON") || !principal.AllowsModel("mitie") {
+		t.Errorf("Expected the union of the public and internal scopes, but got %+v", principal)
+	}
+
+	principal, err = a.Authenticate(signToken(t, "ES256", "k1", key, validClaims("internal")))
+	if err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	if !principal.AllowsModel("perceptron") || principal.AllowsModel("mitie") {
+		t.Errorf("Expected the internal scope to allow only the perceptron model, but got %v", principal.Models)
+	}
+}
+
+func TestKeySet_RefreshesURLOnUnknownKey(t *testing.T) {
+	first, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	second, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keys := map[string]crypto.Signer{"k1": first}
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(jwksFor(keys))
+	}))
+	defer server.Close()
+
+	a := newJWTAuthenticator(t, server.URL)
+	now := time.Now()
+	a.jwt.keys.now = func() time.Time { return now }
+	if _, err := a.Authenticate(signToken(t, "ES256", "k1", first, validClaims("public"))); err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+
+	// The issuer rotates in a new key, which is fetched once the minimum
+	// refresh interval has passed
+	keys = map[string]crypto.Signer{"k1": first, "k2": second}
+	token := signToken(t, "ES256", "k2", second, validClaims("public"))
+	if _, err := a.Authenticate(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected the key set not to be refetched right away, but got %v", err)
+	}
+	now = now.Add(minRefreshInterval)
+	if _, err := a.Authenticate(token); err != nil {
+		t.Errorf("Expected the rotated key to be fetched, but got %v", err)
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("Expected 2 fetches, but got %d", fetches.Load())
+	}
+}
+
+func TestKeySet_SlowRefreshKeepsKnownKeys(t *testing.T) {
+	first, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	second, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keys := jwksFor(map[string]crypto.Signer{"k1": first, "k2": second})
+	var fetches atomic.Int32
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) == 1 {
+			w.Write(jwksFor(map[string]crypto.Signer{"k1": first}))
+			return
+		}
+		<-unblock
+		w.Write(keys)
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	a := newJWTAuthenticator(t, server.URL)
+	now := time.Now().Add(minRefreshInterval)
+	a.jwt.keys.now = func() time.Time { return now }
+
+	// A token with an unknown key waits for the issuer
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := a.Authenticate(signToken(t, "ES256", "k2", second, validClaims("public")))
+		refreshed <- err
+	}()
+	for fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	known := make(chan error, 1)
+	go func() {
+		_, err := a.Authenticate(signToken(t, "ES256", "k1", first, validClaims("public")))
+		known <- err
+	}()
+	select {
+	case err := <-known:
+		if err != nil {
+			t.Errorf("Failed to authenticate with a known key: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a known key not to wait for the issuer")
+	}
+
+	unblock <- struct{}{}
+	if err := <-refreshed; err != nil {
+		t.Errorf("Expected the new key to be fetched, but got %v", err)
+	}
+}
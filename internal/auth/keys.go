@@ -0,0 +1,200 @@
+// Package auth authenticates API clients with API keys or JWT bearer tokens,
+// enforces their request rate limits and character quotas, and keeps their
+// usage.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// keyPrefix starts every API key, followed by the key ID, an underscore and
+// the secret.
+const keyPrefix = "ner_"
+
+var (
+	// ErrInvalidKey is returned for keys that are malformed or unknown.
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrRevokedKey is returned for keys that were revoked.
+	ErrRevokedKey = errors.New("API key has been revoked")
+	// ErrKeyNotFound is returned by Revoke for unknown key IDs.
+	ErrKeyNotFound = errors.New("API key not found")
+)
+
+// Key is an API key as stored in the keys file. Only a hash of the key
+// itself is kept.
+type Key struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Hash    string    `json:"hash"`
+	Created time.Time `json:"created"`
+	// Revoked is set when the key was revoked, and the key is rejected
+	// from then on.
+	Revoked *time.Time `json:"revoked,omitempty"`
+	Limits
+}
+
+// Limits are the request rate and character quotas of a client. Zero means
+// unlimited.
+type Limits struct {
+	// RatePerSecond is the sustained request rate, and Burst how many
+	// requests may be made at once.
+	RatePerSecond float64 `json:"rate_per_second,omitempty"`
+	Burst         int     `json:"burst,omitempty"`
+	// DailyChars and MonthlyChars limit the characters of text extracted
+	// per UTC day and month.
+	DailyChars   int64 `json:"daily_chars,omitempty"`
+	MonthlyChars int64 `json:"monthly_chars,omitempty"`
+}
+
+// KeyFile is the set of API keys, stored as JSON.
+type KeyFile struct {
+	Keys []Key `json:"keys"`
+}
+
+// LoadKeyFile reads the keys file at path. A missing file has no keys.
+func LoadKeyFile(path string) (*KeyFile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &KeyFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys file: %w", err)
+	}
+	var file KeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keys file: %w", err)
+	}
+	return &file, nil
+}
+
+// Save writes the keys file to path, replacing it atomically.
+func (f *KeyFile) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode keys file: %w", err)
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// Create adds a key with the given name and limits and returns it, with the
+// key itself, which is not stored and cannot be recovered.
+func (f *KeyFile) Create(name string, limits Limits) (Key, string, error) {
+	id, err := randomHex(4)
+	if err != nil {
+		return Key{}, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return Key{}, "", err
+	}
+	plaintext := keyPrefix + id + "_" + secret
+
+	key := Key{
+		ID:      id,
+		Name:    name,
+		Hash:    hashKey(plaintext),
+		Created: time.Now().UTC().Truncate(time.Second),
+		Limits:  limits,
+	}
+	f.Keys = append(f.Keys, key)
+	return key, plaintext, nil
+}
+
+// Revoke marks the key with the given ID as revoked.
+func (f *KeyFile) Revoke(id string) error {
+	for i := range f.Keys {
+		if f.Keys[i].ID == id {
+			if f.Keys[i].Revoked == nil {
+				now := time.Now().UTC().Truncate(time.Second)
+				f.Keys[i].Revoked = &now
+			}
+			return nil
+		}
+	}
+	return ErrKeyNotFound
+}
+
+// lookup returns the key matching plaintext.
+func (f *KeyFile) lookup(plaintext string) (*Key, error) {
+	id, ok := keyID(plaintext)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	hash := hashKey(plaintext)
+	for i := range f.Keys {
+		key := &f.Keys[i]
+		if key.ID != id || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) != 1 {
+			continue
+		}
+		if key.Revoked != nil {
+			return nil, ErrRevokedKey
+		}
+		return key, nil
+	}
+	return nil, ErrInvalidKey
+}
+
+// IsAPIKey reports whether token looks like an API key rather than another
+// kind of bearer token.
+func IsAPIKey(token string) bool {
+	_, ok := keyID(token)
+	return ok
+}
+
+func keyID(plaintext string) (string, bool) {
+	rest, ok := strings.CutPrefix(plaintext, keyPrefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	return id, ok && id != "" && secret != ""
+}
+
+func hashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimit is the outcome of a rate limit check, as reported in the
+// RateLimit-* response headers.
+type RateLimit struct {
+	Allowed bool
+	// Limit is the bucket size and Remaining the requests left in it.
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again, and RetryAfter the
+	// time until the next request is allowed when this one was not.
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// bucket is a token bucket refilled at rate tokens per second up to burst.
+type bucket struct {
+	rate    float64
+	burst   float64
+	tokens  float64
+	updated time.Time
+}
+
+func newBucket(rate float64, burst int, now time.Time) *bucket {
+	size := float64(max(burst, 1))
+	return &bucket{rate: rate, burst: size, tokens: size, updated: now}
+}
+
+// take removes a token if one is available.
+func (b *bucket) take(now time.Time) RateLimit {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.updated = now
+	}
+
+	result := RateLimit{Limit: int(b.burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = b.duration(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = b.duration(b.burst - b.tokens)
+	return result
+}
+
+// duration returns the time needed to refill the given number of tokens.
+func (b *bucket) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned by Charge when a request would take a client
+// past its daily or monthly character quota.
+var ErrQuotaExceeded = errors.New("character quota exceeded")
+
+// Usage counts the characters each client extracted in the current UTC day
+// and month. Counters are kept in memory and written to a JSON file by
+// Flush.
+type Usage struct {
+	mu      sync.Mutex
+	path    string
+	clients map[string]*clientUsage
+	dirty   bool
+	// flushing keeps concurrent flushes from writing older counters last
+	flushing sync.Mutex
+}
+
+type clientUsage struct {
+	Day        string `json:"day"`
+	DayChars   int64  `json:"day_chars"`
+	Month      string `json:"month"`
+	MonthChars int64  `json:"month_chars"`
+}
+
+// OpenUsage loads the usage counters stored at path, if any. Flush writes
+// them back there.
+func OpenUsage(path string) (*Usage, error) {
+	u := &Usage{path: path, clients: make(map[string]*clientUsage)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return u, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	if err := json.Unmarshal(data, &u.clients); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file: %w", err)
+	}
+	return u, nil
+}
+
+// Charge adds chars to the client's counters, unless that would exceed one
+// of its quotas, in which case nothing is counted.
+func (u *Usage) Charge(client string, chars int64, limits Limits, now time.Time) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	c := u.current(client, now)
+	if limits.DailyChars > 0 && c.DayChars+chars > limits.DailyChars {
+		return fmt.Errorf("%w: daily limit of %d characters", ErrQuotaExceeded, limits.DailyChars)
+	}
+	if limits.MonthlyChars > 0 && c.MonthChars+chars > limits.MonthlyChars {
+		return fmt.Errorf("%w: monthly limit of %d characters", ErrQuotaExceeded, limits.MonthlyChars)
+	}
+	c.DayChars += chars
+	c.MonthChars += chars
+	u.dirty = true
+	return nil
+}
+
+// Used returns the characters the client extracted today and this month.
+func (u *Usage) Used(client string, now time.Time) (day, month int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	c := u.current(client, now)
+	return c.DayChars, c.MonthChars
+}
+
+// current returns the client's counters, reset when a new day or month
+// started since they were last used.
+func (u *Usage) current(client string, now time.Time) *clientUsage {
+	now = now.UTC()
+	day, month := now.Format("2006-01-02"), now.Format("2006-01")
+
+	c := u.clients[client]
+	if c == nil {
+		c = &clientUsage{Day: day, Month: month}
+		u.clients[client] = c
+	}
+	if c.Day != day {
+		c.Day, c.DayChars = day, 0
+	}
+	if c.Month != month {
+		c.Month, c.MonthChars = month, 0
+	}
+	return c
+}
+
+// Flush writes the counters to the usage file if they changed.
+func (u *Usage) Flush() error {
+	u.flushing.Lock()
+	defer u.flushing.Unlock()
+
+	u.mu.Lock()
+	if !u.dirty {
+		u.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(u.clients, "", "  ")
+	u.dirty = false
+	u.mu.Unlock()
+	if err == nil {
+		err = writeFileAtomic(u.path, append(data, '\n'))
+	}
+	if err != nil {
+		u.mu.Lock()
+		u.dirty = true
+		u.mu.Unlock()
+		return fmt.Errorf("failed to save usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// entriesBucket maps keys to values prefixed with their expiry time and
+	// write sequence number
+	entriesBucket = []byte("entries")
+	// orderBucket maps write sequence numbers to keys, oldest first
+	orderBucket = []byte("order")
+)
+
+// boltHeader is the length of the expiry time and sequence number stored
+// before each value.
+const boltHeader = 16
+
+// Bolt is a cache stored in a bbolt database file. Each value is prefixed
+// with its expiry time in Unix nanoseconds, zero for entries that never
+// expire, and the sequence number of its write. Once the cache holds its
+// maximum number of entries, each write evicts the oldest written entry.
+// Expired entries are removed when read, when evicted and when the file is
+// opened.
+type Bolt struct {
+	db   *bolt.DB
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+	// mu serializes write transactions with the count updates they cause,
+	// so each write starts from the count left by the previous one
+	mu    sync.Mutex
+	count atomic.Int64
+}
+
+// OpenBolt opens or creates the cache database at path, holding at most size
+// entries, each living for ttl, or until evicted when ttl is zero. A size
+// below one means one.
+func OpenBolt(path string, size int, ttl time.Duration) (*Bolt, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache database: %w", err)
+	}
+	c := &Bolt{db: db, size: max(size, 1), ttl: ttl, now: time.Now}
+	if err := c.purge(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// purge creates the buckets if needed, drops expired entries and entries
+// over the size, which may have been lowered since the file was written,
+// and counts the rest.
+func (c *Bolt) purge() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	count := 0
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		entries, err := tx.CreateBucketIfNotExists(entriesBucket)
+		if err != nil {
+			return err
+		}
+		order, err := tx.CreateBucketIfNotExists(orderBucket)
+		if err != nil {
+			return err
+		}
+
+		var expired [][]byte
+		err = entries.ForEach(func(key, value []byte) error {
+			if isExpired(value, now) {
+				expired = append(expired, key)
+			} else {
+				count++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range expired {
+			if err := deleteEntry(entries, order, key); err != nil {
+				return err
+			}
+		}
+		evicted, err := c.evict(entries, order, count)
+		count -= evicted
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize cache database: %w", err)
+	}
+	c.count.Store(int64(count))
+	return nil
+}
+
+// Get treats read errors as misses.
+func (c *Bolt) Get(key string) ([]byte, bool) {
+	var value []byte
+	expired := false
+	c.db.View(func(tx *bolt.Tx) error {
+		stored := tx.Bucket(entriesBucket).Get([]byte(key))
+		switch {
+		case stored == nil:
+		case isExpired(stored, c.now()):
+			expired = true
+		default:
+			// stored is only valid during the transaction
+			value = append([]byte(nil), stored[boltHeader:]...)
+		}
+		return nil
+	})
+	if expired {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		deleted := false
+		err := c.db.Update(func(tx *bolt.Tx) error {
+			entries := tx.Bucket(entriesBucket)
+			// Another writer may have removed or replaced it meanwhile
+			if stored := entries.Get([]byte(key)); stored == nil || !isExpired(stored, c.now()) {
+				return nil
+			}
+			deleted = true
+			return deleteEntry(entries, tx.Bucket(orderBucket), []byte(key))
+		})
+		if err == nil && deleted {
+			c.count.Add(-1)
+		}
+	}
+	return value, value != nil
+}
+
+func (c *Bolt) Set(key string, value []byte) error {
+	stored := make([]byte, boltHeader+len(value))
+	if c.ttl > 0 {
+		binary.BigEndian.PutUint64(stored, uint64(c.now().Add(c.ttl).UnixNano()))
+	}
+	copy(stored[boltHeader:], value)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var delta int64
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		entries, order := tx.Bucket(entriesBucket), tx.Bucket(orderBucket)
+		count := int(c.count.Load())
+		if previous := entries.Get([]byte(key)); previous != nil {
+			if len(previous) >= boltHeader {
+				if err := order.Delete(previous[8:boltHeader]); err != nil {
+					return err
+				}
+			}
+		} else {
+			delta = 1
+			count++
+		}
+
+		seq, err := order.NextSequence()
+		if err != nil {
+			return err
+		}
+		binary.BigEndian.PutUint64(stored[8:boltHeader], seq)
+		if err := order.Put(stored[8:boltHeader], []byte(key)); err != nil {
+			return err
+		}
+		if err := entries.Put([]byte(key), stored); err != nil {
+			return err
+		}
+
+		evicted, err := c.evict(entries, order, count)
+		delta -= int64(evicted)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	c.count.Add(delta)
+	return nil
+}
+
+// evict removes the oldest written entries until at most c.size of count
+// remain, and returns how many it removed.
+func (c *Bolt) evict(entries, order *bolt.Bucket, count int) (int, error) {
+	evicted := 0
+	cursor := order.Cursor()
+	for seq, key := cursor.First(); seq != nil && count-evicted > c.size; seq, key = cursor.First() {
+		if entries.Get(key) != nil {
+			if err := entries.Delete(key); err != nil {
+				return evicted, err
+			}
+			evicted++
+		}
+		if err := cursor.Delete(); err != nil {
+			return evicted, err
+		}
+	}
+	return evicted, nil
+}
+
+// deleteEntry removes key and its place in the write order.
+func deleteEntry(entries, order *bolt.Bucket, key []byte) error {
+	if stored := entries.Get(key); len(stored) >= boltHeader {
+		if err := order.Delete(stored[8:boltHeader]); err != nil {
+			return err
+		}
+	}
+	return entries.Delete(key)
+}
+
+// Len returns the number of entries counted by the cache, without reading
+// the database.
+func (c *Bolt) Len() int {
+	return int(c.count.Load())
+}
+
+func (c *Bolt) Close() error {
+	return c.db.Close()
+}
+
+func isExpired(stored []byte, now time.Time) bool {
+	if len(stored) < boltHeader {
+		return true
+	}
+	expires := int64(binary.BigEndian.Uint64(stored))
+	return expires != 0 && now.UnixNano() > expires
+}
@@ -0,0 +1,55 @@
+// Package cache stores extraction results by key, in memory with
+// least-recently-used eviction or on disk in a bbolt database that survives
+// restarts, with oldest-first eviction. Entries expire after a time to live.
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Backend names accepted by Open.
+const (
+	BackendNone   = "none"
+	BackendMemory = "memory"
+	BackendBolt   = "bolt"
+)
+
+// Cache maps keys to values. Implementations are safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored under key, unless it is missing or has
+	// expired.
+	Get(key string) ([]byte, bool)
+	// Set stores value under key, replacing any previous value.
+	Set(key string, value []byte) error
+	// Len returns the number of entries, including expired ones not yet
+	// removed.
+	Len() int
+	Close() error
+}
+
+// Options configures the cache returned by Open.
+type Options struct {
+	// Backend is BackendNone (the default), BackendMemory or BackendBolt.
+	Backend string
+	// Path is the database file of the bolt backend.
+	Path string
+	// Size is the most entries the cache holds.
+	Size int
+	// TTL is how long entries live. Zero keeps them until evicted.
+	TTL time.Duration
+}
+
+// Open returns the cache described by opts, or nil when caching is disabled.
+func Open(opts Options) (Cache, error) {
+	switch opts.Backend {
+	case "", BackendNone:
+		return nil, nil
+	case BackendMemory:
+		return NewLRU(opts.Size, opts.TTL), nil
+	case BackendBolt:
+		return OpenBolt(opts.Path, opts.Size, opts.TTL)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", opts.Backend)
+	}
+}
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestLRU_Eviction(t *testing.T) {
+	c := NewLRU(2, 0)
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	c.Get("a")
+	c.Set("c", []byte("3"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Expected the least recently used entry to be evicted")
+	}
+	if value, ok := c.Get("a"); !ok || string(value) != "1" {
+		t.Errorf("Expected a=1, but got %q, %v", value, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, but got %d", c.Len())
+	}
+}
+
+func TestLRU_TTL(t *testing.T) {
+	now := time.Now()
+	c := NewLRU(10, time.Minute)
+	c.now = func() time.Time { return now }
+	c.Set("a", []byte("1"))
+
+	now = now.Add(30 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("Expected the entry to be alive before its TTL")
+	}
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Expected the entry to expire after its TTL")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected the expired entry to be removed, but got %d entries", c.Len())
+	}
+}
+
+func TestBolt_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c, err := OpenBolt(path, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	if err := c.Set("a", []byte("1")); err != nil {
+		t.Fatalf("Failed to set entry: %v", err)
+	}
+	c.Close()
+
+	c, err = OpenBolt(path, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to reopen cache: %v", err)
+	}
+	defer c.Close()
+	if value, ok := c.Get("a"); !ok || string(value) != "1" {
+		t.Errorf("Expected a=1 after reopening, but got %q, %v", value, ok)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Expected a miss for a missing key")
+	}
+}
+
+func TestBolt_TTL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c, err := OpenBolt(path, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	defer c.Close()
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Expected the entry to expire after its TTL")
+	}
+	if err := c.purge(); err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected expired entries to be purged, but got %d entries", c.Len())
+	}
+}
+
+func TestBolt_Eviction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c, err := OpenBolt(path, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	defer c.Close()
+
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	// Writing a again makes b the oldest entry
+	c.Set("a", []byte("3"))
+	c.Set("c", []byte("4"))
+
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, but got %d", c.Len())
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Expected the oldest entry to be evicted")
+	}
+	if value, ok := c.Get("a"); !ok || string(value) != "3" {
+		t.Errorf("Expected a=3, but got %q, %v", value, ok)
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Errorf("Expected the newest entry to be kept")
+	}
+}
+
+func TestBolt_SizeLoweredOnOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c, err := OpenBolt(path, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		c.Set(key, []byte(key))
+	}
+	c.Close()
+
+	c, err = OpenBolt(path, 3, 0)
+	if err != nil {
+		t.Fatalf("Failed to reopen cache: %v", err)
+	}
+	defer c.Close()
+	if c.Len() != 3 {
+		t.Errorf("Expected 3 entries after reopening with a lower size, but got %d", c.Len())
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("Expected %s to be evicted", key)
+		}
+	}
+	for _, key := range []string{"c", "d", "e"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+}
+
+func TestBolt_ConcurrentSets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c, err := OpenBolt(path, 5, 0)
+	if err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				c.Set(fmt.Sprintf("%d-%d", i, j), []byte("x"))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stored := 0
+	c.db.View(func(tx *bolt.Tx) error {
+		stored = tx.Bucket(entriesBucket).Stats().KeyN
+		return nil
+	})
+	if stored != 5 || c.Len() != 5 {
+		t.Errorf("Expected 5 stored and counted entries, but got %d stored and %d counted", stored, c.Len())
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if c, err := Open(Options{}); c != nil || err != nil {
+		t.Errorf("Expected no cache by default, but got %v, %v", c, err)
+	}
+	if c, err := Open(Options{Backend: BackendMemory, Size: 5}); err != nil || c == nil {
+		t.Errorf("Expected a memory cache, but got %v, %v", c, err)
+	}
+	if _, err := Open(Options{Backend: "redis"}); err == nil {
+		t.Errorf("Expected an error for an unknown backend")
+	}
+}
@@ -0,0 +1,8 @@
+// Package canary holds the sentence the service checks its models with. It
+// imports nothing, so the ner package and the shared test texts can both
+// use it without an import cycle.
+package canary
+
+// Text is a sentence in which any model fit to serve requests finds the
+// person "María García" and the location "Madrid".
+const Text = "María García vive en Madrid"
@@ -0,0 +1,235 @@
+// Package charset detects the character encoding of input text and
+// transcodes it to UTF-8.
+package charset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+// Encodings reported by Decode.
+const (
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	ISO88591    = "iso-8859-1"
+	Windows1252 = "windows-1252"
+)
+
+// Auto asks Decode to detect the encoding, the same as an empty charset.
+const Auto = "auto"
+
+var (
+	// ErrInvalidUTF8 is returned when input is not valid UTF-8 and no other
+	// encoding was declared or detected.
+	ErrInvalidUTF8 = errors.New("input is not valid UTF-8")
+	// ErrUnknownCharset is returned for charset names that are not supported.
+	ErrUnknownCharset = errors.New("unknown charset")
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// encodings are the encodings reported by Decode.
+var encodings = map[string]encoding.Encoding{
+	UTF8:        encoding.Nop,
+	UTF16LE:     xunicode.UTF16(xunicode.LittleEndian, xunicode.IgnoreBOM),
+	UTF16BE:     xunicode.UTF16(xunicode.BigEndian, xunicode.IgnoreBOM),
+	ISO88591:    charmap.ISO8859_1,
+	Windows1252: charmap.Windows1252,
+}
+
+// aliases maps common labels to the encodings above. Other labels are looked
+// up in the WHATWG encoding index.
+var aliases = map[string]string{
+	"utf8": UTF8, "iso8859-1": ISO88591, "iso_8859-1": ISO88591,
+	"latin1": ISO88591, "latin-1": ISO88591, "l1": ISO88591, "cp1252": Windows1252,
+}
+
+// Decode transcodes data to UTF-8 and returns the text with the name of the
+// encoding it was read as.
+//
+// A declared charset, from an explicit override or an HTTP charset
+// parameter, is trusted. Otherwise a byte order mark decides, then UTF-16 is
+// recognized by its zero bytes, valid UTF-8 is kept as is, and anything else
+// is checked against the Latin-1 family used by legacy Spanish text.
+// ErrInvalidUTF8 is returned when none of them applies. A UTF-8 byte order
+// mark is always removed.
+func Decode(data []byte, declared string) (string, string, error) {
+	declared = strings.ToLower(strings.TrimSpace(declared))
+	if declared != "" && declared != Auto {
+		return decodeDeclared(data, declared)
+	}
+
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return decodeDeclared(data, UTF8)
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decodeDeclared(data[len(bomUTF16LE):], UTF16LE)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return decodeDeclared(data[len(bomUTF16BE):], UTF16BE)
+	}
+
+	// UTF-16 text without a byte order mark can also be valid UTF-8, since
+	// it is mostly ASCII and zero bytes
+	if name := detectUTF16(data); name != "" {
+		if text, _, err := decodeDeclared(data, name); err == nil && isText(text) {
+			return text, name, nil
+		}
+	}
+	if utf8.Valid(data) {
+		return string(data), UTF8, nil
+	}
+	if name := detectLatin(data); name != "" {
+		return decodeDeclared(data, name)
+	}
+	return "", "", fmt.Errorf("%w and its encoding could not be detected; set the charset explicitly", ErrInvalidUTF8)
+}
+
+// DecodeString is Decode for text that is already held in a string, such as
+// a form value or a command-line argument.
+func DecodeString(s, declared string) (string, string, error) {
+	return Decode([]byte(s), declared)
+}
+
+// FromContentType returns the charset parameter of a Content-Type header, or
+// an empty string when it has none.
+func FromContentType(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
+func decodeDeclared(data []byte, name string) (string, string, error) {
+	enc, name, err := lookup(name)
+	if err != nil {
+		return "", "", err
+	}
+
+	if enc == encoding.Nop {
+		data = bytes.TrimPrefix(data, bomUTF8)
+		if !utf8.Valid(data) {
+			return "", "", fmt.Errorf("%w (declared charset %s)", ErrInvalidUTF8, name)
+		}
+		return string(data), name, nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode %s input: %w", name, err)
+	}
+	return string(decoded), name, nil
+}
+
+func lookup(name string) (encoding.Encoding, string, error) {
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	if enc, ok := encodings[name]; ok {
+		return enc, name, nil
+	}
+
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w %q", ErrUnknownCharset, name)
+	}
+	canonical, _ := htmlindex.Name(enc)
+	if canonical == UTF8 {
+		return encoding.Nop, UTF8, nil
+	}
+	return enc, canonical, nil
+}
+
+// detectUTF16 recognizes UTF-16 without a byte order mark from the zero bytes
+// that mostly-ASCII text leaves in every other position.
+func detectUTF16(data []byte) string {
+	if len(data) < 8 || len(data)%2 != 0 {
+		return ""
+	}
+	var evenZeros, oddZeros int
+	for i := 0; i < len(data); i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	pairs := len(data) / 2
+	switch {
+	case oddZeros*10 >= pairs*3 && evenZeros*20 < pairs:
+		return UTF16LE
+	case evenZeros*10 >= pairs*3 && oddZeros*20 < pairs:
+		return UTF16BE
+	}
+	return ""
+}
+
+// isText reports whether decoded text is free of control characters other
+// than whitespace and of replacement characters.
+func isText(text string) bool {
+	for _, r := range text {
+		if r == utf8.RuneError || (unicode.IsControl(r) && !unicode.IsSpace(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// undefinedWindows1252 are the bytes Windows-1252 leaves unassigned.
+var undefinedWindows1252 = map[byte]bool{0x81: true, 0x8D: true, 0x8F: true, 0x90: true, 0x9D: true}
+
+// latinPunctuation are non-letter characters common in Spanish text.
+const latinPunctuation = "¿¡«»ºª°€–—‘’“”…·´¨§\u00a0"
+
+// detectLatin recognizes ISO-8859-1 and Windows-1252 text. Most non-ASCII
+// bytes must decode to letters or common punctuation, and control bytes other
+// than whitespace rule the data out as binary. Bytes in 0x80-0x9F are control
+// characters in ISO-8859-1 but quotes and dashes in Windows-1252, so their
+// presence picks the latter.
+func detectLatin(data []byte) string {
+	var high, plausible int
+	windows := false
+	for _, c := range data {
+		switch {
+		case c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f':
+			return ""
+		case c < 0x80:
+			continue
+		case c < 0xA0:
+			if undefinedWindows1252[c] {
+				return ""
+			}
+			windows = true
+		}
+
+		high++
+		r := charmap.Windows1252.DecodeByte(c)
+		if unicode.IsLetter(r) || strings.ContainsRune(latinPunctuation, r) {
+			plausible++
+		}
+	}
+
+	if high == 0 || plausible*10 < high*8 {
+		return ""
+	}
+	if windows {
+		return Windows1252
+	}
+	return ISO88591
+}
@@ -0,0 +1,80 @@
+package charset
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		declared string
+		text     string
+		encoding string
+	}{
+		{"utf-8", []byte("España"), "", "España", UTF8},
+		{"utf-8 bom", []byte("\xef\xbb\xbfEspaña"), "", "España", UTF8},
+		{"utf-16le bom", []byte("\xff\xfeE\x00s\x00p\x00a\x00\xf1\x00a\x00"), "", "España", UTF16LE},
+		{"utf-16be bom", []byte("\xfe\xff\x00E\x00s\x00p\x00a\x00\xf1\x00a"), "", "España", UTF16BE},
+		{"utf-16le heuristic", []byte("M\x00a\x00d\x00r\x00i\x00d\x00"), "", "Madrid", UTF16LE},
+		{"iso-8859-1 heuristic", []byte("Mar\xeda Garc\xeda vive en Espa\xf1a"), "", "María García vive en España", ISO88591},
+		{"windows-1252 heuristic", []byte("\x93Espa\xf1a\x94 \x96 Le\xf3n"), "", "“España” – León", Windows1252},
+		{"declared latin1", []byte("Espa\xf1a"), "Latin1", "España", ISO88591},
+		{"declared auto", []byte("Espa\xf1a"), "auto", "España", ISO88591},
+		{"declared iso-8859-15", []byte("\xa4"), "iso-8859-15", "€", "iso-8859-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, enc, err := Decode(tt.data, tt.declared)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if text != tt.text {
+				t.Errorf("Expected text %q, but got %q", tt.text, text)
+			}
+			if enc != tt.encoding {
+				t.Errorf("Expected encoding %q, but got %q", tt.encoding, enc)
+			}
+		})
+	}
+}
+
+func TestDecode_Rejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		declared string
+		err      error
+	}{
+		{"binary", []byte("\x00\x01\x02\xff\xfe\x03\x00\x04"), "", ErrInvalidUTF8},
+		{"undefined windows-1252 byte", []byte("Espa\x81a"), "", ErrInvalidUTF8},
+		{"declared utf-8", []byte("Espa\xf1a"), "utf-8", ErrInvalidUTF8},
+		{"unknown charset", []byte("Madrid"), "klingon", ErrUnknownCharset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Decode(tt.data, tt.declared)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected error %v, but got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestFromContentType(t *testing.T) {
+	tests := map[string]string{
+		"text/plain; charset=ISO-8859-1": "ISO-8859-1",
+		"application/json":               "",
+		"text/html;charset=\"utf-8\"":    "utf-8",
+		"not a media type;;":             "",
+	}
+
+	for contentType, expected := range tests {
+		if got := FromContentType(contentType); got != expected {
+			t.Errorf("Expected %q for %q, but got %q", expected, contentType, got)
+		}
+	}
+}
@@ -2,11 +2,55 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	ModelPath string
-	Port      string
+	ModelPath           string
+	Port                string
+	Backend             string
+	PerceptronModelPath string
+	Tokenizer           string
+	SplitContractions   bool
+	SplitClitics        bool
+	Normalization       []string
+	TruecaseModelPath   string
+	HandlesPath         string
+	PoolSize            int
+	QueueSize           int
+	BatchMaxDocuments   int
+	BatchMaxBytes       int
+	StreamMaxLineBytes  int
+	CacheBackend        string
+	CachePath           string
+	CacheSize           int
+	CacheTTL            time.Duration
+	RequestTimeout      time.Duration
+	ShedQueueDepth      int
+	ShedMaxWait         time.Duration
+	APIKeysFile         string
+	APIUsageFile        string
+	JWTJWKS             string
+	JWTJWKSRefresh      time.Duration
+	JWTIssuer           string
+	JWTAudience         string
+	JWTScopesFile       string
+	TLSCertFile         string
+	TLSKeyFile          string
+	TLSMinVersion       string
+	TLSCipherSuites     []string
+	TLSClientCAFile     string
+	TLSClientAuth       string
+	ShutdownGracePeriod time.Duration
+	ModelWatchInterval  time.Duration
+	AdminToken          string
+	TracingExporter     string
+	TracingEndpoint     string
+	TracingSampleRatio  float64
+	LogLevel            string
+	LogPII              bool
 }
 
 func Load() *Config {
@@ -20,8 +64,163 @@ func Load() *Config {
 		port = "8080"
 	}
 
+	backend := os.Getenv("NER_BACKEND")
+	if backend == "" {
+		backend = "mitie"
+	}
+
+	perceptronModelPath := os.Getenv("PERCEPTRON_MODEL_PATH")
+	if perceptronModelPath == "" {
+		perceptronModelPath = "models/perceptron.model"
+	}
+
+	tokenizer := os.Getenv("NER_TOKENIZER")
+	if tokenizer == "" {
+		tokenizer = "spanish"
+	}
+
+	normalization := os.Getenv("NER_NORMALIZATION")
+	if normalization == "" {
+		normalization = "nfc,control,whitespace,quotes"
+	}
+
+	cacheBackend := os.Getenv("NER_CACHE_BACKEND")
+	if cacheBackend == "" {
+		cacheBackend = "none"
+	}
+
+	cachePath := os.Getenv("NER_CACHE_PATH")
+	if cachePath == "" {
+		cachePath = "cache.db"
+	}
+
+	apiUsageFile := os.Getenv("NER_API_USAGE_FILE")
+	if apiUsageFile == "" {
+		apiUsageFile = "usage.json"
+	}
+
+	tlsMinVersion := os.Getenv("NER_TLS_MIN_VERSION")
+	if tlsMinVersion == "" {
+		tlsMinVersion = "1.2"
+	}
+
+	tlsClientAuth := os.Getenv("NER_TLS_CLIENT_AUTH")
+	if tlsClientAuth == "" {
+		tlsClientAuth = "require"
+	}
+
+	logLevel := os.Getenv("NER_LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "info"
+	}
+
+	tracingExporter := os.Getenv("NER_TRACING_EXPORTER")
+	if tracingExporter == "" {
+		tracingExporter = "none"
+	}
+
 	return &Config{
-		ModelPath: modelPath,
-		Port:      port,
+		ModelPath:           modelPath,
+		Port:                port,
+		Backend:             backend,
+		PerceptronModelPath: perceptronModelPath,
+		Tokenizer:           tokenizer,
+		SplitContractions:   getEnvBool("NER_TOKENIZER_SPLIT_CONTRACTIONS"),
+		SplitClitics:        getEnvBool("NER_TOKENIZER_SPLIT_CLITICS"),
+		Normalization:       parseList(normalization),
+		TruecaseModelPath:   os.Getenv("NER_TRUECASE_MODEL_PATH"),
+		HandlesPath:         os.Getenv("NER_SOCIAL_HANDLES_PATH"),
+		PoolSize:            getEnvInt("NER_POOL_SIZE", 1),
+		QueueSize:           getEnvInt("NER_QUEUE_SIZE", 100),
+		BatchMaxDocuments:   getEnvInt("NER_BATCH_MAX_DOCUMENTS", 100),
+		BatchMaxBytes:       getEnvInt("NER_BATCH_MAX_BYTES", 10<<20),
+		StreamMaxLineBytes:  getEnvInt("NER_STREAM_MAX_LINE_BYTES", 10<<20),
+		CacheBackend:        cacheBackend,
+		CachePath:           cachePath,
+		CacheSize:           getEnvInt("NER_CACHE_SIZE", 10000),
+		CacheTTL:            getEnvDuration("NER_CACHE_TTL", 24*time.Hour),
+		RequestTimeout:      getEnvDuration("NER_REQUEST_TIMEOUT", 30*time.Second),
+		ShedQueueDepth:      getEnvInt("NER_SHED_QUEUE_DEPTH", 0),
+		ShedMaxWait:         getEnvDuration("NER_SHED_MAX_WAIT", 10*time.Second),
+		APIKeysFile:         os.Getenv("NER_API_KEYS_FILE"),
+		APIUsageFile:        apiUsageFile,
+		JWTJWKS:             os.Getenv("NER_JWT_JWKS"),
+		JWTJWKSRefresh:      getEnvDuration("NER_JWT_JWKS_REFRESH", time.Hour),
+		JWTIssuer:           os.Getenv("NER_JWT_ISSUER"),
+		JWTAudience:         os.Getenv("NER_JWT_AUDIENCE"),
+		JWTScopesFile:       os.Getenv("NER_JWT_SCOPES_FILE"),
+		TLSCertFile:         os.Getenv("NER_TLS_CERT_FILE"),
+		TLSKeyFile:          os.Getenv("NER_TLS_KEY_FILE"),
+		TLSMinVersion:       tlsMinVersion,
+		TLSCipherSuites:     parseList(os.Getenv("NER_TLS_CIPHER_SUITES")),
+		TLSClientCAFile:     os.Getenv("NER_TLS_CLIENT_CA_FILE"),
+		TLSClientAuth:       tlsClientAuth,
+		ShutdownGracePeriod: getEnvDuration("NER_SHUTDOWN_GRACE_PERIOD", 25*time.Second),
+		ModelWatchInterval:  getEnvDuration("NER_MODEL_WATCH_INTERVAL", 0),
+		AdminToken:          os.Getenv("NER_ADMIN_TOKEN"),
+		TracingExporter:     tracingExporter,
+		TracingEndpoint:     os.Getenv("NER_TRACING_ENDPOINT"),
+		TracingSampleRatio:  getEnvRatio("NER_TRACING_SAMPLE_RATIO", 1),
+		LogLevel:            logLevel,
+		LogPII:              getEnvBool("NER_LOG_PII"),
+	}
+}
+
+// parseList splits a comma-separated list. "none" yields an empty list.
+func parseList(value string) []string {
+	if strings.EqualFold(strings.TrimSpace(value), "none") {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvBool reports whether the environment variable is set to a true value
+// such as "1" or "true".
+func getEnvBool(key string) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	return err == nil && value
+}
+
+// getEnvInt returns the environment variable as a non-negative integer, or
+// fallback when it is unset or invalid.
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// getEnvRatio returns the environment variable as a number between 0 and 1,
+// or fallback when it is unset or invalid.
+func getEnvRatio(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || value < 0 || value > 1 {
+		return fallback
+	}
+	return value
+}
+
+// getEnvDuration returns the environment variable as a non-negative
+// duration such as "30m", or fallback when it is unset or invalid.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// BackendModelPath returns the model file for the configured backend.
+func (c *Config) BackendModelPath() string {
+	if c.Backend == "perceptron" {
+		return c.PerceptronModelPath
 	}
+	return c.ModelPath
 }
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad_DefaultValues(t *testing.T) {
@@ -110,3 +112,367 @@ func TestConfig_Structure(t *testing.T) {
 		t.Errorf("Expected Port 3000, but got %s", config.Port)
 	}
 }
+
+func TestLoad_Backend(t *testing.T) {
+	os.Unsetenv("NER_BACKEND")
+	os.Unsetenv("PERCEPTRON_MODEL_PATH")
+
+	config := Load()
+
+	if config.Backend != "mitie" {
+		t.Errorf("Expected Backend mitie, but got %s", config.Backend)
+	}
+
+	if config.BackendModelPath() != config.ModelPath {
+		t.Errorf("Expected MITIE backend to use ModelPath %s, but got %s", config.ModelPath, config.BackendModelPath())
+	}
+
+	os.Setenv("NER_BACKEND", "perceptron")
+	os.Setenv("PERCEPTRON_MODEL_PATH", "/custom/perceptron.model")
+	defer func() {
+		os.Unsetenv("NER_BACKEND")
+		os.Unsetenv("PERCEPTRON_MODEL_PATH")
+	}()
+
+	config = Load()
+
+	if config.Backend != "perceptron" {
+		t.Errorf("Expected Backend perceptron, but got %s", config.Backend)
+	}
+
+	if config.BackendModelPath() != "/custom/perceptron.model" {
+		t.Errorf("Expected perceptron model path /custom/perceptron.model, but got %s", config.BackendModelPath())
+	}
+}
+
+func TestLoad_Tokenizer(t *testing.T) {
+	os.Unsetenv("NER_TOKENIZER")
+	os.Setenv("NER_TOKENIZER_SPLIT_CLITICS", "true")
+	defer os.Unsetenv("NER_TOKENIZER_SPLIT_CLITICS")
+
+	config := Load()
+
+	if config.Tokenizer != "spanish" {
+		t.Errorf("Expected Tokenizer spanish, but got %s", config.Tokenizer)
+	}
+
+	if config.SplitContractions {
+		t.Error("Expected SplitContractions to default to false")
+	}
+
+	if !config.SplitClitics {
+		t.Error("Expected SplitClitics to be true")
+	}
+}
+
+func TestLoad_Normalization(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{"", []string{"nfc", "control", "whitespace", "quotes"}},
+		{"nfc, quotes", []string{"nfc", "quotes"}},
+		{"none", nil},
+	}
+
+	defer os.Unsetenv("NER_NORMALIZATION")
+	for _, tt := range tests {
+		os.Setenv("NER_NORMALIZATION", tt.value)
+
+		config := Load()
+
+		if strings.Join(config.Normalization, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("NER_NORMALIZATION=%q: expected %v, but got %v", tt.value, tt.expected, config.Normalization)
+		}
+	}
+}
+
+func TestLoad_TruecaseModelPath(t *testing.T) {
+	os.Unsetenv("NER_TRUECASE_MODEL_PATH")
+	if config := Load(); config.TruecaseModelPath != "" {
+		t.Errorf("Expected truecasing to be disabled by default, but got model %q", config.TruecaseModelPath)
+	}
+
+	os.Setenv("NER_TRUECASE_MODEL_PATH", "models/truecase.model")
+	defer os.Unsetenv("NER_TRUECASE_MODEL_PATH")
+
+	if config := Load(); config.TruecaseModelPath != "models/truecase.model" {
+		t.Errorf("Expected TruecaseModelPath 'models/truecase.model', but got '%s'", config.TruecaseModelPath)
+	}
+}
+
+func TestLoad_HandlesPath(t *testing.T) {
+	os.Setenv("NER_SOCIAL_HANDLES_PATH", "models/handles.tsv")
+	defer os.Unsetenv("NER_SOCIAL_HANDLES_PATH")
+
+	if config := Load(); config.HandlesPath != "models/handles.tsv" {
+		t.Errorf("Expected HandlesPath 'models/handles.tsv', but got '%s'", config.HandlesPath)
+	}
+}
+
+func TestLoad_Pool(t *testing.T) {
+	tests := []struct {
+		poolSize, queueSize         string
+		expectedPool, expectedQueue int
+	}{
+		{"", "", 1, 100},
+		{"4", "10", 4, 10},
+		{"invalid", "-1", 1, 100},
+	}
+
+	defer os.Unsetenv("NER_POOL_SIZE")
+	defer os.Unsetenv("NER_QUEUE_SIZE")
+	for _, tt := range tests {
+		os.Setenv("NER_POOL_SIZE", tt.poolSize)
+		os.Setenv("NER_QUEUE_SIZE", tt.queueSize)
+
+		config := Load()
+
+		if config.PoolSize != tt.expectedPool || config.QueueSize != tt.expectedQueue {
+			t.Errorf("NER_POOL_SIZE=%q NER_QUEUE_SIZE=%q: expected %d and %d, but got %d and %d",
+				tt.poolSize, tt.queueSize, tt.expectedPool, tt.expectedQueue, config.PoolSize, config.QueueSize)
+		}
+	}
+}
+
+func TestLoad_BatchLimits(t *testing.T) {
+	config := Load()
+	if config.BatchMaxDocuments != 100 || config.BatchMaxBytes != 10<<20 {
+		t.Errorf("Expected default batch limits of 100 documents and 10 MB, but got %d and %d", config.BatchMaxDocuments, config.BatchMaxBytes)
+	}
+
+	os.Setenv("NER_BATCH_MAX_DOCUMENTS", "500")
+	os.Setenv("NER_BATCH_MAX_BYTES", "1048576")
+	defer os.Unsetenv("NER_BATCH_MAX_DOCUMENTS")
+	defer os.Unsetenv("NER_BATCH_MAX_BYTES")
+
+	config = Load()
+	if config.BatchMaxDocuments != 500 || config.BatchMaxBytes != 1048576 {
+		t.Errorf("Expected batch limits of 500 documents and 1048576 bytes, but got %d and %d", config.BatchMaxDocuments, config.BatchMaxBytes)
+	}
+}
+
+func TestLoad_StreamMaxLineBytes(t *testing.T) {
+	config := Load()
+	if config.StreamMaxLineBytes != 10<<20 {
+		t.Errorf("Expected a default stream line limit of 10 MB, but got %d", config.StreamMaxLineBytes)
+	}
+
+	os.Setenv("NER_STREAM_MAX_LINE_BYTES", "65536")
+	defer os.Unsetenv("NER_STREAM_MAX_LINE_BYTES")
+
+	config = Load()
+	if config.StreamMaxLineBytes != 65536 {
+		t.Errorf("Expected a stream line limit of 65536 bytes, but got %d", config.StreamMaxLineBytes)
+	}
+}
+
+func TestLoad_Cache(t *testing.T) {
+	config := Load()
+	if config.CacheBackend != "none" || config.CachePath != "cache.db" || config.CacheSize != 10000 || config.CacheTTL != 24*time.Hour {
+		t.Errorf("Expected the cache disabled with default settings, but got %q, %q, %d, %v",
+			config.CacheBackend, config.CachePath, config.CacheSize, config.CacheTTL)
+	}
+
+	os.Setenv("NER_CACHE_BACKEND", "bolt")
+	os.Setenv("NER_CACHE_PATH", "/var/cache/ner.db")
+	os.Setenv("NER_CACHE_SIZE", "500")
+	os.Setenv("NER_CACHE_TTL", "90m")
+	defer os.Unsetenv("NER_CACHE_BACKEND")
+	defer os.Unsetenv("NER_CACHE_PATH")
+	defer os.Unsetenv("NER_CACHE_SIZE")
+	defer os.Unsetenv("NER_CACHE_TTL")
+
+	config = Load()
+	if config.CacheBackend != "bolt" || config.CachePath != "/var/cache/ner.db" || config.CacheSize != 500 || config.CacheTTL != 90*time.Minute {
+		t.Errorf("Expected a bolt cache at /var/cache/ner.db with 500 entries and a 90m TTL, but got %q, %q, %d, %v",
+			config.CacheBackend, config.CachePath, config.CacheSize, config.CacheTTL)
+	}
+
+	os.Setenv("NER_CACHE_TTL", "soon")
+	if config = Load(); config.CacheTTL != 24*time.Hour {
+		t.Errorf("Expected an invalid TTL to fall back to 24h, but got %v", config.CacheTTL)
+	}
+}
+
+func TestLoad_RequestTimeout(t *testing.T) {
+	config := Load()
+	if config.RequestTimeout != 30*time.Second {
+		t.Errorf("Expected a default request timeout of 30s, but got %v", config.RequestTimeout)
+	}
+
+	os.Setenv("NER_REQUEST_TIMEOUT", "0")
+	defer os.Unsetenv("NER_REQUEST_TIMEOUT")
+	if config = Load(); config.RequestTimeout != 0 {
+		t.Errorf("Expected no request timeout, but got %v", config.RequestTimeout)
+	}
+
+	os.Setenv("NER_REQUEST_TIMEOUT", "5s")
+	if config = Load(); config.RequestTimeout != 5*time.Second {
+		t.Errorf("Expected a request timeout of 5s, but got %v", config.RequestTimeout)
+	}
+}
+
+func TestLoad_LoadShedding(t *testing.T) {
+	config := Load()
+	if config.ShedQueueDepth != 0 || config.ShedMaxWait != 10*time.Second {
+		t.Errorf("Expected no depth limit and a 10s wait limit, but got %d and %v", config.ShedQueueDepth, config.ShedMaxWait)
+	}
+
+	os.Setenv("NER_SHED_QUEUE_DEPTH", "20")
+	os.Setenv("NER_SHED_MAX_WAIT", "2s")
+	defer os.Unsetenv("NER_SHED_QUEUE_DEPTH")
+	defer os.Unsetenv("NER_SHED_MAX_WAIT")
+
+	config = Load()
+	if config.ShedQueueDepth != 20 || config.ShedMaxWait != 2*time.Second {
+		t.Errorf("Expected a depth limit of 20 and a 2s wait limit, but got %d and %v", config.ShedQueueDepth, config.ShedMaxWait)
+	}
+}
+
+func TestLoad_APIKeys(t *testing.T) {
+	config := Load()
+	if config.APIKeysFile != "" || config.APIUsageFile != "usage.json" {
+		t.Errorf("Expected API keys disabled and usage in usage.json, but got %q and %q", config.APIKeysFile, config.APIUsageFile)
+	}
+
+	os.Setenv("NER_API_KEYS_FILE", "/etc/ner/keys.json")
+	os.Setenv("NER_API_USAGE_FILE", "/var/lib/ner/usage.json")
+	defer os.Unsetenv("NER_API_KEYS_FILE")
+	defer os.Unsetenv("NER_API_USAGE_FILE")
+
+	config = Load()
+	if config.APIKeysFile != "/etc/ner/keys.json" || config.APIUsageFile != "/var/lib/ner/usage.json" {
+		t.Errorf("Expected the configured key and usage files, but got %q and %q", config.APIKeysFile, config.APIUsageFile)
+	}
+}
+
+func TestLoad_JWT(t *testing.T) {
+	config := Load()
+	if config.JWTJWKS != "" || config.JWTJWKSRefresh != time.Hour {
+		t.Errorf("Expected JWTs disabled with an hourly JWKS refresh, but got %q and %v", config.JWTJWKS, config.JWTJWKSRefresh)
+	}
+
+	os.Setenv("NER_JWT_JWKS", "https://issuer.example/.well-known/jwks.json")
+	os.Setenv("NER_JWT_JWKS_REFRESH", "15m")
+	os.Setenv("NER_JWT_ISSUER", "https://issuer.example")
+	os.Setenv("NER_JWT_AUDIENCE", "ner-service")
+	os.Setenv("NER_JWT_SCOPES_FILE", "/etc/ner/scopes.json")
+	defer os.Unsetenv("NER_JWT_JWKS")
+	defer os.Unsetenv("NER_JWT_JWKS_REFRESH")
+	defer os.Unsetenv("NER_JWT_ISSUER")
+	defer os.Unsetenv("NER_JWT_AUDIENCE")
+	defer os.Unsetenv("NER_JWT_SCOPES_FILE")
+
+	config = Load()
+	if config.JWTJWKS != "https://issuer.example/.well-known/jwks.json" || config.JWTJWKSRefresh != 15*time.Minute {
+		t.Errorf("Expected the configured JWKS refreshed every 15m, but got %q and %v", config.JWTJWKS, config.JWTJWKSRefresh)
+	}
+	if config.JWTIssuer != "https://issuer.example" || config.JWTAudience != "ner-service" || config.JWTScopesFile != "/etc/ner/scopes.json" {
+		t.Errorf("Expected the configured issuer, audience and scopes file, but got %q, %q and %q", config.JWTIssuer, config.JWTAudience, config.JWTScopesFile)
+	}
+}
+
+func TestLoad_TLS(t *testing.T) {
+	config := Load()
+	if config.TLSCertFile != "" || config.TLSMinVersion != "1.2" || config.TLSCipherSuites != nil || config.TLSClientAuth != "require" {
+		t.Errorf("Expected TLS disabled with TLS 1.2, default ciphers and required client certificates, but got %+v", config)
+	}
+
+	os.Setenv("NER_TLS_CERT_FILE", "/etc/ner/tls.crt")
+	os.Setenv("NER_TLS_KEY_FILE", "/etc/ner/tls.key")
+	os.Setenv("NER_TLS_MIN_VERSION", "1.3")
+	os.Setenv("NER_TLS_CIPHER_SUITES", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+	os.Setenv("NER_TLS_CLIENT_CA_FILE", "/etc/ner/clients.pem")
+	os.Setenv("NER_TLS_CLIENT_AUTH", "optional")
+	defer os.Unsetenv("NER_TLS_CERT_FILE")
+	defer os.Unsetenv("NER_TLS_KEY_FILE")
+	defer os.Unsetenv("NER_TLS_MIN_VERSION")
+	defer os.Unsetenv("NER_TLS_CIPHER_SUITES")
+	defer os.Unsetenv("NER_TLS_CLIENT_CA_FILE")
+	defer os.Unsetenv("NER_TLS_CLIENT_AUTH")
+
+	config = Load()
+	if config.TLSCertFile != "/etc/ner/tls.crt" || config.TLSKeyFile != "/etc/ner/tls.key" || config.TLSMinVersion != "1.3" {
+		t.Errorf("Expected the configured certificate, key and version, but got %q, %q and %q", config.TLSCertFile, config.TLSKeyFile, config.TLSMinVersion)
+	}
+	if len(config.TLSCipherSuites) != 2 || config.TLSCipherSuites[1] != "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" {
+		t.Errorf("Expected 2 cipher suites, but got %v", config.TLSCipherSuites)
+	}
+	if config.TLSClientCAFile != "/etc/ner/clients.pem" || config.TLSClientAuth != "optional" {
+		t.Errorf("Expected optional client certificates against clients.pem, but got %q and %q", config.TLSClientCAFile, config.TLSClientAuth)
+	}
+}
+
+func TestLoad_ShutdownGracePeriod(t *testing.T) {
+	config := Load()
+	if config.ShutdownGracePeriod != 25*time.Second {
+		t.Errorf("Expected a grace period of 25s, but got %v", config.ShutdownGracePeriod)
+	}
+
+	os.Setenv("NER_SHUTDOWN_GRACE_PERIOD", "1m")
+	defer os.Unsetenv("NER_SHUTDOWN_GRACE_PERIOD")
+
+	config = Load()
+	if config.ShutdownGracePeriod != time.Minute {
+		t.Errorf("Expected a grace period of 1m, but got %v", config.ShutdownGracePeriod)
+	}
+}
+
+func TestLoad_ModelReload(t *testing.T) {
+	config := Load()
+	if config.ModelWatchInterval != 0 || config.AdminToken != "" {
+		t.Errorf("Expected model watching and the admin endpoint disabled, but got %v and %q", config.ModelWatchInterval, config.AdminToken)
+	}
+
+	os.Setenv("NER_MODEL_WATCH_INTERVAL", "10s")
+	os.Setenv("NER_ADMIN_TOKEN", "s3cret")
+	defer os.Unsetenv("NER_MODEL_WATCH_INTERVAL")
+	defer os.Unsetenv("NER_ADMIN_TOKEN")
+
+	config = Load()
+	if config.ModelWatchInterval != 10*time.Second || config.AdminToken != "s3cret" {
+		t.Errorf("Expected a 10s watch interval and the admin token, but got %v and %q", config.ModelWatchInterval, config.AdminToken)
+	}
+}
+
+func TestLoad_Tracing(t *testing.T) {
+	config := Load()
+	if config.TracingExporter != "none" || config.TracingEndpoint != "" || config.TracingSampleRatio != 1 {
+		t.Errorf("Expected tracing disabled with every trace sampled, but got %q, %q and %v", config.TracingExporter, config.TracingEndpoint, config.TracingSampleRatio)
+	}
+
+	os.Setenv("NER_TRACING_EXPORTER", "otlp")
+	os.Setenv("NER_TRACING_ENDPOINT", "http://collector:4318")
+	os.Setenv("NER_TRACING_SAMPLE_RATIO", "0.25")
+	defer os.Unsetenv("NER_TRACING_EXPORTER")
+	defer os.Unsetenv("NER_TRACING_ENDPOINT")
+	defer os.Unsetenv("NER_TRACING_SAMPLE_RATIO")
+
+	config = Load()
+	if config.TracingExporter != "otlp" || config.TracingEndpoint != "http://collector:4318" || config.TracingSampleRatio != 0.25 {
+		t.Errorf("Expected OTLP export to the collector with a ratio of 0.25, but got %q, %q and %v", config.TracingExporter, config.TracingEndpoint, config.TracingSampleRatio)
+	}
+
+	os.Setenv("NER_TRACING_SAMPLE_RATIO", "2")
+	if config = Load(); config.TracingSampleRatio != 1 {
+		t.Errorf("Expected an out of range ratio to fall back to 1, but got %v", config.TracingSampleRatio)
+	}
+}
+
+func TestLoad_Logging(t *testing.T) {
+	config := Load()
+	if config.LogLevel != "info" || config.LogPII {
+		t.Errorf("Expected info logs without personal data, but got %q and %v", config.LogLevel, config.LogPII)
+	}
+
+	os.Setenv("NER_LOG_LEVEL", "debug")
+	os.Setenv("NER_LOG_PII", "true")
+	defer os.Unsetenv("NER_LOG_LEVEL")
+	defer os.Unsetenv("NER_LOG_PII")
+
+	config = Load()
+	if config.LogLevel != "debug" || !config.LogPII {
+		t.Errorf("Expected debug logs with personal data, but got %q and %v", config.LogLevel, config.LogPII)
+	}
+}
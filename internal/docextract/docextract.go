@@ -0,0 +1,234 @@
+// Package docextract extracts plain text from office documents, PDFs and
+// e-books, keeping track of the page or paragraph each part came from.
+package docextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"ner-service-go/internal/charset"
+)
+
+// Formats recognized by Detect.
+const (
+	FormatText = "text"
+	FormatHTML = "html"
+	FormatDOCX = "docx"
+	FormatODT  = "odt"
+	FormatPDF  = "pdf"
+	FormatRTF  = "rtf"
+	FormatEPUB = "epub"
+)
+
+// maxPartSize limits how much is decompressed from a single archive entry,
+// so a crafted document cannot exhaust memory.
+const maxPartSize = 64 << 20
+
+// paragraphSeparator is placed between paragraphs and pages in Text.
+const paragraphSeparator = "\n\n"
+
+// ErrUnsupportedFormat is returned for documents that cannot be read.
+var ErrUnsupportedFormat = errors.New("unsupported document format")
+
+// Document is the text extracted from a file.
+type Document struct {
+	Format string
+	// Charset is the encoding plain text and HTML files were read as.
+	Charset  string
+	Text     string
+	Sections []Section
+}
+
+// Section is the byte range [Start, End) of Text that came from one page or
+// paragraph. Page and Paragraph are 1-based and zero when the format does not
+// provide them.
+type Section struct {
+	Start     int
+	End       int
+	Page      int
+	Paragraph int
+}
+
+// Detect returns the format of data, looking at its content first and at the
+// file name's extension second.
+func Detect(name string, data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return FormatPDF
+	case bytes.HasPrefix(data, []byte(`{\rtf`)):
+		return FormatRTF
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		if format := detectZip(data); format != "" {
+			return format
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".pdf":
+		return FormatPDF
+	case ".rtf":
+		return FormatRTF
+	case ".docx":
+		return FormatDOCX
+	case ".odt":
+		return FormatODT
+	case ".epub":
+		return FormatEPUB
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML
+	}
+
+	head := strings.ToLower(string(bytes.TrimSpace(data[:min(len(data), 512)])))
+	if strings.HasPrefix(head, "<!doctype html") || strings.HasPrefix(head, "<html") {
+		return FormatHTML
+	}
+	return FormatText
+}
+
+// detectZip tells the zip-based formats apart by their mimetype entry or
+// their main part.
+func detectZip(data []byte) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ""
+	}
+	if mimetype, err := readZipFile(zr, "mimetype"); err == nil {
+		switch strings.TrimSpace(string(mimetype)) {
+		case "application/epub+zip":
+			return FormatEPUB
+		case "application/vnd.oasis.opendocument.text":
+			return FormatODT
+		}
+	}
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			return FormatDOCX
+		}
+	}
+	return ""
+}
+
+// Extract detects the format of data and extracts its text. Plain text and
+// HTML files are transcoded to UTF-8 from declaredCharset, or from the
+// encoding detected by the charset package when it is empty.
+func Extract(name string, data []byte, declaredCharset string) (*Document, error) {
+	format := Detect(name, data)
+
+	var encoding string
+	if format == FormatText || format == FormatHTML {
+		text, enc, err := charset.Decode(data, declaredCharset)
+		if err != nil {
+			return nil, err
+		}
+		data, encoding = []byte(text), enc
+	}
+
+	var doc *Document
+	var err error
+	switch format {
+	case FormatDOCX:
+		doc, err = extractDOCX(data)
+	case FormatODT:
+		doc, err = extractODT(data)
+	case FormatPDF:
+		doc, err = extractPDF(data)
+	case FormatRTF:
+		doc, err = extractRTF(data)
+	case FormatEPUB:
+		doc, err = extractEPUB(data)
+	case FormatHTML:
+		doc, err = extractHTML(data)
+	default:
+		text := string(data)
+		doc = &Document{Text: text, Sections: []Section{{Start: 0, End: len(text)}}}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s text: %w", format, err)
+	}
+
+	doc.Format = format
+	doc.Charset = encoding
+	return doc, nil
+}
+
+// IsDocument reports whether format needs text extraction, as opposed to
+// plain text.
+func IsDocument(format string) bool {
+	return format != FormatText
+}
+
+// builder accumulates paragraphs and pages into a Document.
+type builder struct {
+	text      strings.Builder
+	sections  []Section
+	paragraph int
+}
+
+// addParagraph appends a paragraph, skipping blank ones so paragraph numbers
+// match what a reader sees.
+func (b *builder) addParagraph(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	b.paragraph++
+	b.add(text, Section{Paragraph: b.paragraph})
+}
+
+// addPage appends the text of a page.
+func (b *builder) addPage(page int, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	b.add(text, Section{Page: page})
+}
+
+func (b *builder) add(text string, section Section) {
+	if b.text.Len() > 0 {
+		b.text.WriteString(paragraphSeparator)
+	}
+	section.Start = b.text.Len()
+	b.text.WriteString(text)
+	section.End = b.text.Len()
+	b.sections = append(b.sections, section)
+}
+
+func (b *builder) document() *Document {
+	return &Document{Text: b.text.String(), Sections: b.sections}
+}
+
+func readZipFile(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxPartSize {
+			return nil, fmt.Errorf("%s is larger than %d bytes", name, maxPartSize)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s not found in archive", name)
+}
+
+func openZip(data []byte) (*zip.Reader, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	return zr, nil
+}
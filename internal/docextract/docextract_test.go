@@ -0,0 +1,232 @@
+package docextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"ner-service-go/internal/charset"
+)
+
+// zipFiles builds an archive with the given entries, in order.
+func zipFiles(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f[0])
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", f[0], err)
+		}
+		w.Write([]byte(f[1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// minimalPDF builds a PDF with one page of Helvetica text per argument.
+func minimalPDF(pages ...string) []byte {
+	var objects []string
+	kids := make([]string, len(pages))
+	for i, text := range pages {
+		pageID := 4 + 2*i
+		kids[i] = fmt.Sprintf("%d 0 R", pageID)
+		content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageID+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}, objects...)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func sectionTexts(doc *Document) []string {
+	texts := make([]string, len(doc.Sections))
+	for i, s := range doc.Sections {
+		texts[i] = doc.Text[s.Start:s.End]
+	}
+	return texts
+}
+
+func TestExtract_DOCX(t *testing.T) {
+	data := zipFiles(t,
+		[2]string{"[Content_Types].xml", "<Types/>"},
+		[2]string{"word/document.xml", `<?xml version="1.0"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>María García</w:t></w:r><w:r><w:t xml:space="preserve"> vive en Madrid</w:t></w:r></w:p>
+<w:p></w:p>
+<w:p><w:r><w:instrText>HYPERLINK "x"</w:instrText><w:t>Trabaja en Telefónica</w:t></w:r></w:p>
+</w:body></w:document>`})
+
+	doc, err := Extract("informe.docx", data, "")
+	if err != nil {
+		t.Fatalf("Failed to extract DOCX: %v", err)
+	}
+
+	if doc.Format != FormatDOCX {
+		t.Errorf("Expected format docx, but got %s", doc.Format)
+	}
+	expected := []string{"María García vive en Madrid", "Trabaja en Telefónica"}
+	texts := sectionTexts(doc)
+	if strings.Join(texts, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected paragraphs %q, but got %q", expected, texts)
+	}
+	if doc.Sections[1].Paragraph != 2 {
+		t.Errorf("Expected paragraph number 2, but got %d", doc.Sections[1].Paragraph)
+	}
+}
+
+func TestExtract_ODT(t *testing.T) {
+	data := zipFiles(t,
+		[2]string{"mimetype", "application/vnd.oasis.opendocument.text"},
+		[2]string{"content.xml", `<?xml version="1.0"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:text>
+<text:h>Informe</text:h>
+<text:p>Pedro<text:s/>Sánchez visitó <text:span>Barcelona</text:span></text:p>
+</office:text></office:body></office:document-content>`})
+
+	doc, err := Extract("informe.bin", data, "")
+	if err != nil {
+		t.Fatalf("Failed to extract ODT: %v", err)
+	}
+
+	if doc.Format != FormatODT {
+		t.Errorf("Expected format odt, but got %s", doc.Format)
+	}
+	expected := []string{"Informe", "Pedro Sánchez visitó Barcelona"}
+	texts := sectionTexts(doc)
+	if strings.Join(texts, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected paragraphs %q, but got %q", expected, texts)
+	}
+}
+
+func TestExtract_EPUB(t *testing.T) {
+	data := zipFiles(t,
+		[2]string{"mimetype", "application/epub+zip"},
+		[2]string{"META-INF/container.xml", `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`},
+		[2]string{"OEBPS/content.opf", `<package><manifest>
+<item id="c2" href="text/c2.xhtml"/><item id="c1" href="text/c1.xhtml"/>
+</manifest><spine><itemref idref="c1"/><itemref idref="c2"/></spine></package>`},
+		[2]string{"OEBPS/text/c1.xhtml", `<html><body><h1>Capítulo 1</h1><p>Don Quijote vivía en La Mancha.</p></body></html>`},
+		[2]string{"OEBPS/text/c2.xhtml", `<html><body><p>Sancho Panza llegó.</p></body></html>`})
+
+	doc, err := Extract("libro.epub", data, "")
+	if err != nil {
+		t.Fatalf("Failed to extract EPUB: %v", err)
+	}
+
+	expected := []string{"Capítulo 1", "Don Quijote vivía en La Mancha.", "Sancho Panza llegó."}
+	texts := sectionTexts(doc)
+	if strings.Join(texts, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected paragraphs %q in spine order, but got %q", expected, texts)
+	}
+	if doc.Sections[2].Paragraph != 3 {
+		t.Errorf("Expected paragraphs numbered across chapters, but got %d", doc.Sections[2].Paragraph)
+	}
+}
+
+func TestExtract_RTF(t *testing.T) {
+	data := []byte(`{\rtf1\ansi\ansicpg1252{\fonttbl{\f0 Arial;}}{\*\generator Word;}` +
+		`\f0 Mar\'eda Garc\'eda vive en Espa\u241?a.\par` +
+		`{\header Cabecera\par}Trabaja en \{Telef\'f3nica\}.\par}`)
+
+	doc, err := Extract("carta.rtf", data, "")
+	if err != nil {
+		t.Fatalf("Failed to extract RTF: %v", err)
+	}
+
+	expected := []string{"María García vive en España.", "Trabaja en {Telefónica}."}
+	texts := sectionTexts(doc)
+	if strings.Join(texts, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected paragraphs %q, but got %q", expected, texts)
+	}
+}
+
+func TestExtract_PDF(t *testing.T) {
+	doc, err := Extract("informe.pdf", minimalPDF("Pedro visita Madrid", "Ana vive en Sevilla"), "")
+	if err != nil {
+		t.Fatalf("Failed to extract PDF: %v", err)
+	}
+
+	if len(doc.Sections) != 2 {
+		t.Fatalf("Expected 2 pages, but got %d: %q", len(doc.Sections), doc.Text)
+	}
+	for i, section := range doc.Sections {
+		if section.Page != i+1 {
+			t.Errorf("Expected page %d, but got %d", i+1, section.Page)
+		}
+	}
+	if !strings.Contains(doc.Text[doc.Sections[1].Start:doc.Sections[1].End], "Sevilla") {
+		t.Errorf("Expected second page to contain Sevilla, but got %q", doc.Text)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"notas.txt", "María García vive en Madrid", FormatText},
+		{"sin-extension", "%PDF-1.7", FormatPDF},
+		{"documento", `{\rtf1\ansi}`, FormatRTF},
+		{"pagina.html", "Hola", FormatHTML},
+		{"pagina", "<!DOCTYPE html><html></html>", FormatHTML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if format := Detect(tt.name, []byte(tt.data)); format != tt.expected {
+				t.Errorf("Expected format %s, but got %s", tt.expected, format)
+			}
+		})
+	}
+}
+
+func TestExtract_InvalidArchive(t *testing.T) {
+	if _, err := Extract("roto.docx", []byte("PK\x03\x04garbage"), ""); err == nil {
+		t.Error("Expected an error for a corrupt DOCX file")
+	}
+}
+
+func TestExtract_Latin1Text(t *testing.T) {
+	doc, err := Extract("archivo.txt", []byte("Vivo en Espa\xf1a"), "")
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if doc.Text != "Vivo en España" {
+		t.Errorf("Expected text %q, but got %q", "Vivo en España", doc.Text)
+	}
+	if doc.Charset != charset.ISO88591 {
+		t.Errorf("Expected charset %s, but got %s", charset.ISO88591, doc.Charset)
+	}
+
+	if _, err := Extract("archivo.txt", []byte("Vivo en Espa\xf1a"), "utf-8"); !errors.Is(err, charset.ErrInvalidUTF8) {
+		t.Errorf("Expected ErrInvalidUTF8 for a wrong override, but got %v", err)
+	}
+}
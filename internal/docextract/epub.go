@@ -0,0 +1,99 @@
+package docextract
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+
+	"ner-service-go/internal/htmltext"
+)
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Manifest []struct {
+		ID   string `xml:"id,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// extractEPUB reads the XHTML documents of the spine in reading order and
+// numbers their paragraphs across the whole book.
+func extractEPUB(data []byte) (*Document, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	containerXML, err := readZipFile(zr, "META-INF/container.xml")
+	if err != nil {
+		return nil, err
+	}
+	var container epubContainer
+	if err := xml.Unmarshal(containerXML, &container); err != nil {
+		return nil, fmt.Errorf("invalid container.xml: %w", err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, fmt.Errorf("container.xml has no rootfile")
+	}
+
+	opfPath := container.Rootfiles[0].FullPath
+	opfXML, err := readZipFile(zr, opfPath)
+	if err != nil {
+		return nil, err
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(opfXML, &pkg); err != nil {
+		return nil, fmt.Errorf("invalid package document: %w", err)
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = path.Join(path.Dir(opfPath), item.Href)
+	}
+
+	b := &builder{}
+	for _, itemref := range pkg.Spine {
+		href, ok := hrefs[itemref.IDRef]
+		if !ok {
+			continue
+		}
+		content, err := readZipFile(zr, href)
+		if err != nil {
+			return nil, err
+		}
+		if err := addHTMLParagraphs(b, content); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.document(), nil
+}
+
+// extractHTML numbers the block-level elements of an HTML document as
+// paragraphs.
+func extractHTML(data []byte) (*Document, error) {
+	b := &builder{}
+	if err := addHTMLParagraphs(b, data); err != nil {
+		return nil, err
+	}
+	return b.document(), nil
+}
+
+func addHTMLParagraphs(b *builder, content []byte) error {
+	doc, err := htmltext.Parse(string(content))
+	if err != nil {
+		return err
+	}
+	for _, block := range doc.Blocks {
+		b.addParagraph(doc.Text[block.Start:block.End])
+	}
+	return nil
+}
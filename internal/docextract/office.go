@@ -0,0 +1,128 @@
+package docextract
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// extractDOCX reads the paragraphs of word/document.xml. Field codes and
+// tracked deletions are skipped.
+func extractDOCX(data []byte) (*Document, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	content, err := readZipFile(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &builder{}
+	var para strings.Builder
+	inText := false
+
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.addParagraph(para.String())
+				para.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		}
+	}
+	b.addParagraph(para.String())
+
+	return b.document(), nil
+}
+
+// extractODT reads the paragraphs and headings of content.xml.
+func extractODT(data []byte) (*Document, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	content, err := readZipFile(zr, "content.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &builder{}
+	var para strings.Builder
+	depth := 0
+
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p", "h":
+				depth++
+			case "s":
+				// text:s is a run of text:c spaces
+				count := 1
+				for _, a := range t.Attr {
+					if a.Name.Local == "c" {
+						if n, err := strconv.Atoi(a.Value); err == nil && n > 0 {
+							count = n
+						}
+					}
+				}
+				para.WriteString(strings.Repeat(" ", count))
+			case "tab":
+				para.WriteString("\t")
+			case "line-break":
+				para.WriteString("\n")
+			}
+		case xml.EndElement:
+			if (t.Name.Local == "p" || t.Name.Local == "h") && depth > 0 {
+				depth--
+				if depth == 0 {
+					b.addParagraph(para.String())
+					para.Reset()
+				}
+			}
+		case xml.CharData:
+			if depth > 0 {
+				para.Write(t)
+			}
+		}
+	}
+
+	return b.document(), nil
+}
@@ -0,0 +1,208 @@
+package docextract
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// rtfSkippedDestinations are groups that hold no document text.
+var rtfSkippedDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true,
+	"pict": true, "object": true, "fldinst": true, "header": true,
+	"headerl": true, "headerr": true, "headerf": true, "footer": true,
+	"footerl": true, "footerr": true, "footerf": true, "themedata": true,
+	"datastore": true, "latentstyles": true, "xmlnstbl": true,
+	"listtable": true, "listoverridetable": true, "rsidtbl": true,
+	"generator": true, "colorschememapping": true, "filetbl": true,
+	"revtbl": true, "bkmkstart": true, "bkmkend": true,
+}
+
+// rtfSymbols are control words that stand for a character.
+var rtfSymbols = map[string]string{
+	"tab": "\t", "line": "\n", "emdash": "—", "endash": "–", "bullet": "•",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+}
+
+type rtfState struct {
+	skip bool
+	uc   int
+}
+
+// rtfReader is a minimal RTF reader that keeps paragraph text and decodes
+// \'hh escapes (Windows-1252) and \uN Unicode escapes.
+type rtfReader struct {
+	data     []byte
+	pos      int
+	stack    []rtfState
+	para     strings.Builder
+	pending  []byte
+	skipNext int
+	b        *builder
+}
+
+func extractRTF(data []byte) (*Document, error) {
+	r := &rtfReader{
+		data:  data,
+		stack: []rtfState{{uc: 1}},
+		b:     &builder{},
+	}
+	r.run()
+	r.endParagraph()
+	return r.b.document(), nil
+}
+
+func (r *rtfReader) state() *rtfState {
+	return &r.stack[len(r.stack)-1]
+}
+
+func (r *rtfReader) run() {
+	for r.pos < len(r.data) {
+		c := r.data[r.pos]
+		switch c {
+		case '{':
+			r.flushBytes()
+			r.stack = append(r.stack, *r.state())
+			r.pos++
+		case '}':
+			r.flushBytes()
+			if len(r.stack) > 1 {
+				r.stack = r.stack[:len(r.stack)-1]
+			}
+			r.pos++
+		case '\\':
+			r.pos++
+			r.controlSequence()
+		case '\r', '\n':
+			r.pos++
+		default:
+			r.writeByte(c)
+			r.pos++
+		}
+	}
+}
+
+func (r *rtfReader) controlSequence() {
+	if r.pos >= len(r.data) {
+		return
+	}
+	c := r.data[r.pos]
+	switch {
+	case c == '\\' || c == '{' || c == '}':
+		r.writeByte(c)
+		r.pos++
+	case c == '\'':
+		if r.pos+2 < len(r.data) {
+			if v, err := strconv.ParseUint(string(r.data[r.pos+1:r.pos+3]), 16, 8); err == nil {
+				r.writeByte(byte(v))
+			}
+		}
+		r.pos += 3
+	case c == '*':
+		r.state().skip = true
+		r.pos++
+	case c == '~':
+		r.writeString(" ")
+		r.pos++
+	case c == '_':
+		r.writeString("-")
+		r.pos++
+	case isASCIILetter(c):
+		r.controlWord()
+	default:
+		// Other control symbols, such as \- (optional hyphen)
+		r.pos++
+	}
+}
+
+func (r *rtfReader) controlWord() {
+	start := r.pos
+	for r.pos < len(r.data) && isASCIILetter(r.data[r.pos]) {
+		r.pos++
+	}
+	word := string(r.data[start:r.pos])
+
+	paramStart := r.pos
+	if r.pos < len(r.data) && r.data[r.pos] == '-' {
+		r.pos++
+	}
+	for r.pos < len(r.data) && r.data[r.pos] >= '0' && r.data[r.pos] <= '9' {
+		r.pos++
+	}
+	param, hasParam := 0, r.pos > paramStart
+	if hasParam {
+		param, _ = strconv.Atoi(string(r.data[paramStart:r.pos]))
+	}
+	// A single space delimits the control word and is not text
+	if r.pos < len(r.data) && r.data[r.pos] == ' ' {
+		r.pos++
+	}
+
+	switch {
+	case rtfSkippedDestinations[word]:
+		r.state().skip = true
+	case word == "par" || word == "sect" || word == "page" || word == "row":
+		if !r.state().skip {
+			r.endParagraph()
+		}
+	case word == "cell":
+		r.writeString("\t")
+	case word == "uc" && hasParam:
+		r.state().uc = param
+	case word == "u" && hasParam:
+		if param < 0 {
+			param += 65536
+		}
+		r.writeString(string(rune(param)))
+		r.skipNext = r.state().uc
+	default:
+		if symbol, ok := rtfSymbols[word]; ok {
+			r.writeString(symbol)
+		}
+	}
+}
+
+// writeByte adds a text byte in the document code page. Bytes following a
+// \uN escape are its ASCII fallback and are dropped.
+func (r *rtfReader) writeByte(c byte) {
+	if r.skipNext > 0 {
+		r.skipNext--
+		return
+	}
+	if r.state().skip {
+		return
+	}
+	r.pending = append(r.pending, c)
+}
+
+func (r *rtfReader) writeString(s string) {
+	if r.state().skip {
+		return
+	}
+	r.flushBytes()
+	r.para.WriteString(s)
+}
+
+// flushBytes decodes pending code page bytes into the paragraph.
+func (r *rtfReader) flushBytes() {
+	if len(r.pending) == 0 {
+		return
+	}
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(r.pending)
+	if err != nil {
+		decoded = r.pending
+	}
+	r.para.Write(decoded)
+	r.pending = r.pending[:0]
+}
+
+func (r *rtfReader) endParagraph() {
+	r.flushBytes()
+	r.b.addParagraph(r.para.String())
+	r.para.Reset()
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,285 @@
+// Package htmltext extracts the visible text of an HTML document and maps
+// offsets in that text back to DOM text nodes.
+package htmltext
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"ner-service-go/internal/offsetmap"
+)
+
+// blockSeparator is placed between the text of block-level elements so they
+// read as separate sentences.
+const blockSeparator = "\n\n"
+
+// Document is the visible text of an HTML document.
+type Document struct {
+	// Text is the visible text with whitespace collapsed as a browser would
+	// render it and blank lines between block-level elements.
+	Text string
+	// Blocks are the ranges of Text covered by each block of content.
+	Blocks []Block
+
+	source string
+	root   *html.Node
+	nodes  []textNode
+}
+
+// Block is the byte range [Start, End) of one block of content in Text.
+type Block struct {
+	Start int
+	End   int
+}
+
+// Location is part of a text range inside one DOM text node. Start and End
+// are byte offsets in the node's decoded text.
+type Location struct {
+	Path  string `json:"path"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Span is a text range to highlight in Annotate.
+type Span struct {
+	Start int
+	End   int
+	Tag   string
+}
+
+// textNode records where a DOM text node's collapsed text lives in Text.
+type textNode struct {
+	node    *html.Node
+	path    string
+	start   int
+	end     int
+	offsets *offsetmap.Map
+}
+
+// skippedElements never contain visible text.
+var skippedElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Iframe: true, atom.Object: true, atom.Svg: true,
+	atom.Math: true, atom.Select: true, atom.Textarea: true,
+}
+
+// blockElements start and end a block of text.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Br: true, atom.Caption: true, atom.Dd: true, atom.Div: true, atom.Dl: true,
+	atom.Dt: true, atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true,
+	atom.Footer: true, atom.Form: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true, atom.Hr: true,
+	atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true, atom.P: true,
+	atom.Pre: true, atom.Section: true, atom.Table: true, atom.Td: true, atom.Th: true,
+	atom.Title: true, atom.Tr: true, atom.Ul: true,
+}
+
+// Parse extracts the visible text of source.
+func Parse(source string) (*Document, error) {
+	root, err := html.Parse(strings.NewReader(source))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	doc := &Document{source: source, root: root}
+	w := &walker{doc: doc}
+	w.walk(root, "")
+	w.breakBlock()
+	doc.Text = w.text.String()
+
+	return doc, nil
+}
+
+type walker struct {
+	doc          *Document
+	text         strings.Builder
+	blockStart   int
+	inBlock      bool
+	pendingSpace bool
+}
+
+func (w *walker) walk(n *html.Node, path string) {
+	counts := make(map[string]int)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			counts[c.Data]++
+			childPath := fmt.Sprintf("%s/%s[%d]", path, c.Data, counts[c.Data])
+			if skippedElements[c.DataAtom] || isHidden(c) {
+				continue
+			}
+			block := blockElements[c.DataAtom]
+			if block {
+				w.breakBlock()
+			}
+			w.walk(c, childPath)
+			if block {
+				w.breakBlock()
+			}
+		case html.TextNode:
+			counts["text()"]++
+			w.addText(c, fmt.Sprintf("%s/text()[%d]", path, counts["text()"]))
+		}
+	}
+}
+
+// addText appends the node's text with whitespace collapsed.
+func (w *walker) addText(n *html.Node, path string) {
+	data := n.Data
+	b := offsetmap.NewBuilder(len(data))
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRuneInString(data[i:])
+		if unicode.IsSpace(r) {
+			w.pendingSpace = true
+			i += size
+			continue
+		}
+		if w.pendingSpace && (w.inBlock || b.Len() > 0) {
+			b.Write(" ", i, i)
+		}
+		w.pendingSpace = false
+		b.Write(data[i:i+size], i, i+size)
+		i += size
+	}
+
+	if b.Len() == 0 {
+		return
+	}
+	if !w.inBlock {
+		if w.text.Len() > 0 {
+			w.text.WriteString(blockSeparator)
+		}
+		w.blockStart = w.text.Len()
+		w.inBlock = true
+	}
+
+	collapsed, offsets := b.Finish(len(data))
+	start := w.text.Len()
+	w.text.WriteString(collapsed)
+	w.doc.nodes = append(w.doc.nodes, textNode{
+		node:    n,
+		path:    path,
+		start:   start,
+		end:     w.text.Len(),
+		offsets: offsets,
+	})
+}
+
+// breakBlock closes the current block, if it has any text.
+func (w *walker) breakBlock() {
+	w.pendingSpace = false
+	if !w.inBlock {
+		return
+	}
+	w.doc.Blocks = append(w.doc.Blocks, Block{Start: w.blockStart, End: w.text.Len()})
+	w.inBlock = false
+}
+
+// isHidden reports elements hidden with the hidden attribute or an inline
+// display:none style.
+func isHidden(n *html.Node) bool {
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "hidden":
+			return true
+		case "style":
+			style := strings.ReplaceAll(strings.ToLower(a.Val), " ", "")
+			if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Locate returns the DOM text node ranges covering [start, end) of Text.
+func (d *Document) Locate(start, end int) []Location {
+	var locations []Location
+	for _, tn := range d.overlapping(start, end) {
+		s, e := tn.offsets.Span(max(start, tn.start)-tn.start, min(end, tn.end)-tn.start)
+		locations = append(locations, Location{Path: tn.path, Start: s, End: e})
+	}
+	return locations
+}
+
+// overlapping returns the text nodes that overlap [start, end).
+func (d *Document) overlapping(start, end int) []textNode {
+	first := sort.Search(len(d.nodes), func(i int) bool { return d.nodes[i].end > start })
+	var nodes []textNode
+	for i := first; i < len(d.nodes) && d.nodes[i].start < end; i++ {
+		nodes = append(nodes, d.nodes[i])
+	}
+	return nodes
+}
+
+// Annotate returns the source document re-rendered with every span wrapped in
+// <span class="ner-entity" data-tag="TAG"> elements. Spans crossing element
+// boundaries are wrapped separately in each text node they touch. Overlapping
+// spans are ignored after the first.
+func (d *Document) Annotate(spans []Span) (string, error) {
+	// Work on a fresh parse so the Document stays unchanged
+	fresh, err := Parse(d.source)
+	if err != nil {
+		return "", err
+	}
+
+	type cut struct {
+		start, end int
+		tag        string
+	}
+	cuts := make(map[*html.Node][]cut)
+	var order []*html.Node
+
+	sorted := append([]Span(nil), spans...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	covered := 0
+	for _, span := range sorted {
+		if span.Start < covered || span.End <= span.Start {
+			continue
+		}
+		covered = span.End
+		for _, tn := range fresh.overlapping(span.Start, span.End) {
+			s, e := tn.offsets.Span(max(span.Start, tn.start)-tn.start, min(span.End, tn.end)-tn.start)
+			if _, ok := cuts[tn.node]; !ok {
+				order = append(order, tn.node)
+			}
+			cuts[tn.node] = append(cuts[tn.node], cut{start: s, end: e, tag: span.Tag})
+		}
+	}
+
+	for _, n := range order {
+		data := n.Data
+		pos := 0
+		for _, c := range cuts[n] {
+			if c.start > pos {
+				n.Parent.InsertBefore(&html.Node{Type: html.TextNode, Data: data[pos:c.start]}, n)
+			}
+			wrapper := &html.Node{
+				Type:     html.ElementNode,
+				Data:     "span",
+				DataAtom: atom.Span,
+				Attr: []html.Attribute{
+					{Key: "class", Val: "ner-entity"},
+					{Key: "data-tag", Val: c.tag},
+				},
+			}
+			wrapper.AppendChild(&html.Node{Type: html.TextNode, Data: data[c.start:c.end]})
+			n.Parent.InsertBefore(wrapper, n)
+			pos = c.end
+		}
+		n.Data = data[pos:]
+	}
+
+	var out strings.Builder
+	if err := html.Render(&out, fresh.root); err != nil {
+		return "", fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,96 @@
+package htmltext
+
+import (
+	"strings"
+	"testing"
+)
+
+const page = `<!DOCTYPE html>
+<html>
+<head><title>Noticias</title><style>.a { color: red }</style></head>
+<body>
+  <script>var div = "Pedro";</script>
+  <h1>Pedro   Sánchez visita Barcelona</h1>
+  <div class="entry"><p>Reunión con <b>Telefónica</b> en Madrid.</p>
+  <p hidden>Oculto</p></div>
+</body>
+</html>`
+
+func TestParse_VisibleText(t *testing.T) {
+	doc, err := Parse(page)
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	expected := "Pedro Sánchez visita Barcelona\n\nReunión con Telefónica en Madrid."
+	if doc.Text != expected {
+		t.Errorf("Expected text %q, but got %q", expected, doc.Text)
+	}
+
+	if len(doc.Blocks) != 2 {
+		t.Fatalf("Expected 2 blocks, but got %d", len(doc.Blocks))
+	}
+	if block := doc.Text[doc.Blocks[1].Start:doc.Blocks[1].End]; block != "Reunión con Telefónica en Madrid." {
+		t.Errorf("Expected second block to be the paragraph, but got %q", block)
+	}
+}
+
+func TestDocument_Locate(t *testing.T) {
+	doc, err := Parse(page)
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	start := strings.Index(doc.Text, "Sánchez")
+	locations := doc.Locate(start, start+len("Sánchez"))
+	if len(locations) != 1 {
+		t.Fatalf("Expected 1 location, but got %d", len(locations))
+	}
+
+	loc := locations[0]
+	if loc.Path != "/html[1]/body[1]/h1[1]/text()[1]" {
+		t.Errorf("Expected path /html[1]/body[1]/h1[1]/text()[1], but got %s", loc.Path)
+	}
+	// The text node keeps the original run of spaces
+	if nodeText := "Pedro   Sánchez visita Barcelona"; nodeText[loc.Start:loc.End] != "Sánchez" {
+		t.Errorf("Expected node offsets to cover Sánchez, but got %q", nodeText[loc.Start:loc.End])
+	}
+
+	start = strings.Index(doc.Text, "Telefónica")
+	locations = doc.Locate(start, start+len("Telefónica"))
+	if len(locations) != 1 || locations[0].Path != "/html[1]/body[1]/div[1]/p[1]/b[1]/text()[1]" {
+		t.Errorf("Expected Telefónica inside the <b> element, but got %+v", locations)
+	}
+}
+
+func TestDocument_Annotate(t *testing.T) {
+	doc, err := Parse(`<p>Vive en <i>Madrid</i> con Ana García.</p>`)
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	madrid := strings.Index(doc.Text, "Madrid")
+	ana := strings.Index(doc.Text, "Ana García")
+	annotated, err := doc.Annotate([]Span{
+		{Start: madrid, End: madrid + len("Madrid"), Tag: "LOCATION"},
+		{Start: ana, End: ana + len("Ana García"), Tag: "PERSON"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to annotate HTML: %v", err)
+	}
+
+	expected := []string{
+		`<i><span class="ner-entity" data-tag="LOCATION">Madrid</span></i>`,
+		` con <span class="ner-entity" data-tag="PERSON">Ana García</span>.`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(annotated, e) {
+			t.Errorf("Expected annotated HTML to contain %q, but got %s", e, annotated)
+		}
+	}
+
+	// Annotating does not change the parsed document
+	if doc.Text != "Vive en Madrid con Ana García." {
+		t.Errorf("Expected document text to be unchanged, but got %q", doc.Text)
+	}
+}
@@ -0,0 +1,140 @@
+// Package metrics exports the service's metrics in the Prometheus
+// exposition format: HTTP requests, extractions, the extractor pool, the
+// result cache and the loaded model.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"ner-service-go/internal/ner"
+)
+
+const namespace = "ner"
+
+// Metrics records requests and extractions and serves them with the state
+// of a Service.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+
+	tokenizeDuration prometheus.Histogram
+	extractDuration  prometheus.Histogram
+	inputCharacters  prometheus.Histogram
+	inputTokens      prometheus.Histogram
+	entities         *prometheus.CounterVec
+}
+
+// New returns metrics registered with a new registry, along with the Go
+// runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time to serve HTTP requests, by route, method and status code.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"route", "method", "status"}),
+		tokenizeDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tokenize_duration_seconds",
+			Help:      "Time to tokenize texts.",
+			Buckets:   prometheus.ExponentialBuckets(.0001, 4, 9),
+		}),
+		extractDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "extract_duration_seconds",
+			Help:      "Time the model takes to find the entities of tokenized texts.",
+			Buckets:   prometheus.ExponentialBuckets(.0005, 4, 9),
+		}),
+		inputCharacters: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "input_characters",
+			Help:      "Size of the texts extracted, in characters after normalization.",
+			Buckets:   prometheus.ExponentialBuckets(16, 4, 9),
+		}),
+		inputTokens: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "input_tokens",
+			Help:      "Size of the texts extracted, in tokens.",
+			Buckets:   prometheus.ExponentialBuckets(4, 4, 9),
+		}),
+		entities: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "entities_total",
+			Help:      "Entities found by the model, by tag.",
+		}, []string{"tag"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.tokenizeDuration,
+		m.extractDuration,
+		m.inputCharacters,
+		m.inputTokens,
+		m.entities,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// ObserveExtraction records the size, timing and entities of an
+// extraction. It implements ner.Observer.
+func (m *Metrics) ObserveExtraction(e ner.Extraction) {
+	m.inputCharacters.Observe(float64(e.Characters))
+	m.inputTokens.Observe(float64(e.Tokens))
+	m.tokenizeDuration.Observe(e.TokenizeDuration.Seconds())
+	if e.Tokens > 0 {
+		m.extractDuration.Observe(e.ExtractDuration.Seconds())
+	}
+	for _, entity := range e.Entities {
+		m.entities.WithLabelValues(entity.Tag).Inc()
+	}
+}
+
+// WatchService adds the pool, cache and model state of svc to the metrics,
+// read when they are scraped.
+func (m *Metrics) WatchService(svc *ner.Service) {
+	m.registry.MustRegister(serviceCollector{svc})
+}
+
+// Middleware counts and times requests by route. Requests to unknown routes
+// share the route label "unmatched" and no method, so that scanners cannot
+// create unbounded series.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route, method := c.FullPath(), c.Request.Method
+		if route == "" {
+			route, method = "unmatched", ""
+		}
+		labels := prometheus.Labels{
+			"route":  route,
+			"method": method,
+			"status": strconv.Itoa(c.Writer.Status()),
+		}
+		m.requests.With(labels).Inc()
+		m.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"ner-service-go/internal/ner"
+	"ner-service-go/internal/perceptron"
+	"ner-service-go/internal/testutil"
+)
+
+// scrape returns the metrics in the exposition format.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func expectLines(t *testing.T, exposition string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(exposition, "\n"+line+"\n") {
+			t.Errorf("Expected the line %q in the metrics", line)
+		}
+	}
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+	r := gin.New()
+	r.Use(m.Middleware())
+	r.GET("/items/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, path := range []string{"/items/1", "/items/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	expectLines(t, scrape(t, m),
+		`ner_http_requests_total{method="GET",route="/items/:id",status="204"} 2`,
+		`ner_http_requests_total{method="",route="unmatched",status="404"} 1`,
+		`ner_http_request_duration_seconds_count{method="GET",route="/items/:id",status="204"} 2`,
+	)
+}
+
+func TestMetrics_ObserveExtraction(t *testing.T) {
+	m := New()
+	m.ObserveExtraction(ner.Extraction{
+		Characters: 30,
+		Tokens:     6,
+		Entities:   []ner.Entity{{Tag: "PERSON"}, {Tag: "LOCATION"}, {Tag: "PERSON"}},
+	})
+	m.ObserveExtraction(ner.Extraction{Characters: 1})
+
+	expectLines(t, scrape(t, m),
+		`ner_entities_total{tag="PERSON"} 2`,
+		`ner_entities_total{tag="LOCATION"} 1`,
+		`ner_input_characters_count 2`,
+		`ner_input_characters_sum 31`,
+		`ner_input_tokens_sum 6`,
+		`ner_tokenize_duration_seconds_count 2`,
+		// Texts without tokens never reach the model
+		`ner_extract_duration_seconds_count 1`,
+	)
+}
+
+func TestMetrics_WatchService(t *testing.T) {
+	sentences, err := perceptron.ReadCoNLL(strings.NewReader(testutil.SpanishTrainingData))
+	if err != nil {
+		t.Fatalf("Failed to read training data: %v", err)
+	}
+	model, err := perceptron.Train(sentences, perceptron.TrainOptions{Iterations: 5})
+	if err != nil {
+		t.Fatalf("Failed to train model: %v", err)
+	}
+	modelPath := filepath.Join(t.TempDir(), "perceptron.model")
+	if err := model.SaveFile(modelPath); err != nil {
+		t.Fatalf("Failed to save model: %v", err)
+	}
+
+	m := New()
+	svc, err := ner.NewService(ner.Options{Backend: ner.BackendPerceptron, ModelPath: modelPath, PoolSize: 2, Observer: m})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	defer svc.Close()
+	m.WatchService(svc)
+
+	if _, err := svc.ExtractEntities(context.Background(), testutil.SpanishTestTexts.PersonLocation); err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+
+	exposition := scrape(t, m)
+	expectLines(t, exposition,
+		`ner_pool_workers 2`,
+		`ner_pool_busy_workers 0`,
+		`ner_pool_acquired_total 1`,
+		`ner_pool_queue_depth{priority="high"} 0`,
+		`ner_model_info{backend="perceptron",generation="1",path="`+modelPath+`"} 1`,
+		`ner_model_reload_failed 0`,
+		`ner_coalesced_total 0`,
+	)
+	if strings.Contains(exposition, "ner_cache_hits_total") {
+		t.Errorf("Expected no cache metrics without a cache")
+	}
+}
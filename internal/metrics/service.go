@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"ner-service-go/internal/ner"
+)
+
+var (
+	poolWorkersDesc = prometheus.NewDesc(namespace+"_pool_workers",
+		"Extractor instances in the pool.", nil, nil)
+	poolBusyDesc = prometheus.NewDesc(namespace+"_pool_busy_workers",
+		"Extractor instances in use.", nil, nil)
+	poolUtilizationDesc = prometheus.NewDesc(namespace+"_pool_utilization",
+		"Fraction of extractor time spent busy since the model was loaded.", nil, nil)
+	queueDepthDesc = prometheus.NewDesc(namespace+"_pool_queue_depth",
+		"Requests waiting for an extractor, by priority.", []string{"priority"}, nil)
+	queueCapacityDesc = prometheus.NewDesc(namespace+"_pool_queue_capacity",
+		"Requests that may wait for an extractor before new ones are rejected.", nil, nil)
+	acquiredDesc = prometheus.NewDesc(namespace+"_pool_acquired_total",
+		"Extractors handed to requests since the model was loaded.", nil, nil)
+	rejectedDesc = prometheus.NewDesc(namespace+"_pool_rejected_total",
+		"Requests rejected because the queue was full since the model was loaded.", nil, nil)
+	waitDesc = prometheus.NewDesc(namespace+"_pool_wait_seconds_total",
+		"Time requests spent waiting for an extractor since the model was loaded.", nil, nil)
+
+	cacheEntriesDesc = prometheus.NewDesc(namespace+"_cache_entries",
+		"Results in the result cache.", nil, nil)
+	cacheHitsDesc = prometheus.NewDesc(namespace+"_cache_hits_total",
+		"Results served from the result cache.", nil, nil)
+	cacheMissesDesc = prometheus.NewDesc(namespace+"_cache_misses_total",
+		"Results not found in the result cache.", nil, nil)
+	cacheErrorsDesc = prometheus.NewDesc(namespace+"_cache_errors_total",
+		"Results that could not be stored in the result cache.", nil, nil)
+	coalescedDesc = prometheus.NewDesc(namespace+"_coalesced_total",
+		"Requests that shared the extraction of an identical concurrent request.", nil, nil)
+
+	modelInfoDesc = prometheus.NewDesc(namespace+"_model_info",
+		"The model serving requests, as labels.", []string{"backend", "path", "generation"}, nil)
+	modelLoadDesc = prometheus.NewDesc(namespace+"_model_load_seconds",
+		"Time taken to load the model serving requests.", nil, nil)
+	modelLoadedDesc = prometheus.NewDesc(namespace+"_model_loaded_timestamp_seconds",
+		"When the model serving requests was loaded, in Unix time.", nil, nil)
+	reloadFailedDesc = prometheus.NewDesc(namespace+"_model_reload_failed",
+		"1 when the last model reload failed and the previous model kept serving requests.", nil, nil)
+)
+
+// serviceCollector reads the pool, cache and model state of a Service when
+// metrics are scraped. Pool counters restart when a new model is loaded.
+type serviceCollector struct {
+	svc *ner.Service
+}
+
+func (c serviceCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		poolWorkersDesc, poolBusyDesc, poolUtilizationDesc, queueDepthDesc,
+		queueCapacityDesc, acquiredDesc, rejectedDesc, waitDesc,
+		cacheEntriesDesc, cacheHitsDesc, cacheMissesDesc, cacheErrorsDesc,
+		coalescedDesc, modelInfoDesc, modelLoadDesc, modelLoadedDesc,
+		reloadFailedDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (c serviceCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.svc.Stats()
+
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(poolWorkersDesc, float64(stats.Pool.Size))
+	gauge(poolBusyDesc, float64(stats.Pool.Busy))
+	gauge(poolUtilizationDesc, stats.Pool.Utilization)
+	gauge(queueDepthDesc, float64(stats.Pool.QueueDepthHigh), "high")
+	gauge(queueDepthDesc, float64(stats.Pool.QueueDepthLow), "low")
+	gauge(queueCapacityDesc, float64(stats.Pool.QueueCapacity))
+	counter(acquiredDesc, float64(stats.Pool.Acquired))
+	counter(rejectedDesc, float64(stats.Pool.Rejected))
+	counter(waitDesc, stats.Pool.TotalWaitSeconds)
+
+	if stats.Cache != nil {
+		gauge(cacheEntriesDesc, float64(stats.Cache.Entries))
+		counter(cacheHitsDesc, float64(stats.Cache.Hits))
+		counter(cacheMissesDesc, float64(stats.Cache.Misses))
+		counter(cacheErrorsDesc, float64(stats.Cache.Errors))
+	}
+	counter(coalescedDesc, float64(stats.Coalesced))
+
+	model := stats.Model
+	gauge(modelInfoDesc, 1, model.Backend, model.Path, strconv.Itoa(model.Generation))
+	gauge(modelLoadDesc, model.LoadSeconds)
+	gauge(modelLoadedDesc, float64(model.LoadedAt.UnixNano())/1e9)
+	reloadFailed := 0.0
+	if model.LastReloadError != "" {
+		reloadFailed = 1
+	}
+	gauge(reloadFailedDesc, reloadFailed)
+}
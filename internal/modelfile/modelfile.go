@@ -0,0 +1,79 @@
+// Package modelfile reads and writes the framing shared by the model files
+// of the service: a magic header, which identifies the kind of model and its
+// format version, followed by a gzip-compressed gob encoding.
+package modelfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrWrongMagic is returned when a file does not start with the expected
+// magic header.
+var ErrWrongMagic = errors.New("unexpected model file header")
+
+// Write writes magic followed by the compressed gob encoding of v.
+func Write(w io.Writer, magic []byte, v any) error {
+	if _, err := w.Write(magic); err != nil {
+		return fmt.Errorf("failed to write model header: %w", err)
+	}
+	zw := gzip.NewWriter(w)
+	if err := gob.NewEncoder(zw).Encode(v); err != nil {
+		return fmt.Errorf("failed to encode model: %w", err)
+	}
+	return zw.Close()
+}
+
+// Read checks that r starts with magic and decodes the rest into v.
+func Read(r io.Reader, magic []byte, v any) error {
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.Equal(header, magic) {
+		return ErrWrongMagic
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to decompress model: %w", err)
+	}
+	defer zr.Close()
+
+	if err := gob.NewDecoder(zr).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode model: %w", err)
+	}
+	return nil
+}
+
+// WriteFile creates path and writes it with save.
+func WriteFile(path string, save func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create model file: %w", err)
+	}
+	bw := bufio.NewWriter(f)
+	if err := save(bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write model file: %w", err)
+	}
+	return f.Close()
+}
+
+// ReadFile opens path and reads it with load.
+func ReadFile[T any](path string, load func(io.Reader) (T, error)) (T, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("failed to open model file: %w", err)
+	}
+	defer f.Close()
+
+	return load(bufio.NewReader(f))
+}
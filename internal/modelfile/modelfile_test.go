@@ -0,0 +1,75 @@
+package modelfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+var testMagic = []byte("NERTEST\x01")
+
+type testModel struct {
+	Words []string
+}
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testMagic, &testModel{Words: []string{"Madrid", "Sevilla"}}); err != nil {
+		t.Fatalf("Failed to write model: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), testMagic) {
+		t.Errorf("Expected the file to start with the magic header")
+	}
+
+	var model testModel
+	if err := Read(&buf, testMagic, &model); err != nil {
+		t.Fatalf("Failed to read model: %v", err)
+	}
+	if len(model.Words) != 2 || model.Words[1] != "Sevilla" {
+		t.Errorf("Expected words [Madrid Sevilla], but got %v", model.Words)
+	}
+}
+
+func TestRead_WrongMagic(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, []byte("NEROTHR\x01"), &testModel{}); err != nil {
+		t.Fatalf("Failed to write model: %v", err)
+	}
+
+	var model testModel
+	if err := Read(&buf, testMagic, &model); !errors.Is(err, ErrWrongMagic) {
+		t.Errorf("Expected ErrWrongMagic, but got %v", err)
+	}
+	if err := Read(bytes.NewReader([]byte("NER")), testMagic, &model); !errors.Is(err, ErrWrongMagic) {
+		t.Errorf("Expected ErrWrongMagic for a short file, but got %v", err)
+	}
+}
+
+func TestWriteFileReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.model")
+	err := WriteFile(path, func(w io.Writer) error {
+		return Write(w, testMagic, &testModel{Words: []string{"Madrid"}})
+	})
+	if err != nil {
+		t.Fatalf("Failed to write model file: %v", err)
+	}
+
+	model, err := ReadFile(path, func(r io.Reader) (*testModel, error) {
+		var model testModel
+		return &model, Read(r, testMagic, &model)
+	})
+	if err != nil {
+		t.Fatalf("Failed to read model file: %v", err)
+	}
+	if len(model.Words) != 1 || model.Words[0] != "Madrid" {
+		t.Errorf("Expected words [Madrid], but got %v", model.Words)
+	}
+
+	if _, err := ReadFile(filepath.Join(t.TempDir(), "missing.model"), func(r io.Reader) (*testModel, error) {
+		return nil, nil
+	}); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
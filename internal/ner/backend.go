@@ -0,0 +1,87 @@
+package ner
+
+import (
+	"fmt"
+	"strings"
+
+	"ner-service-go/internal/perceptron"
+)
+
+// Backend names accepted in Options.Backend.
+const (
+	BackendMITIE      = "mitie"
+	BackendPerceptron = "perceptron"
+)
+
+// backend is a named entity recognizer driven by the Service.
+type backend interface {
+	tokenize(text string) []string
+	extract(tokens []string) ([]detection, error)
+	close()
+}
+
+// detection is an entity found by a backend, given as a token range with the
+// tag already mapped to the standard format.
+type detection struct {
+	tag   string
+	score float64
+	start int
+	end   int
+}
+
+func newBackend(name, modelPath string) (backend, error) {
+	switch name {
+	case "", BackendMITIE:
+		return newMITIEBackend(modelPath)
+	case BackendPerceptron:
+		return newPerceptronBackend(modelPath)
+	default:
+		return nil, fmt.Errorf("unknown NER backend %q", name)
+	}
+}
+
+// perceptronBackend runs a pure-Go averaged perceptron model.
+type perceptronBackend struct {
+	model *perceptron.Model
+}
+
+func newPerceptronBackend(modelPath string) (backend, error) {
+	model, err := perceptron.LoadFile(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load perceptron model: %w", err)
+	}
+	return &perceptronBackend{model: model}, nil
+}
+
+func (b *perceptronBackend) tokenize(text string) []string {
+	return perceptron.Tokenize(text)
+}
+
+func (b *perceptronBackend) extract(tokens []string) ([]detection, error) {
+	spans := b.model.Extract(tokens)
+	result := make([]detection, len(spans))
+	for i, span := range spans {
+		result[i] = detection{
+			tag:   mapLabelToStandardFormat(span.Tag),
+			score: span.Score,
+			start: span.Start,
+			end:   span.End,
+		}
+	}
+	return result, nil
+}
+
+func (b *perceptronBackend) close() {}
+
+func mapLabelToStandardFormat(label string) string {
+	switch strings.ToUpper(label) {
+	case "LOC", "LOCATION":
+		return "LOCATION"
+	case "ORG", "ORGANIZATION":
+		return "ORGANIZATION"
+	case "PER", "PERSON":
+		return "PERSON"
+	default:
+		return "MISC"
+	}
+}
@@ -0,0 +1,162 @@
+package ner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// CacheStatus tells whether a result was served from the result cache.
+type CacheStatus string
+
+const (
+	CacheHit  CacheStatus = "hit"
+	CacheMiss CacheStatus = "miss"
+)
+
+// CacheStats describes the result cache of a Service.
+type CacheStats struct {
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	// Errors counts results that could not be stored.
+	Errors uint64 `json:"errors"`
+}
+
+type cacheCounters struct {
+	hits, misses, errors, coalesced atomic.Uint64
+}
+
+// ExtractCached returns the result of extract for text and opts, from the
+// result cache when an identical request was served before by the same model
+// and settings. Identical requests arriving while one is being extracted
+// wait for it and share its result instead of occupying an extractor each,
+// so the result must not be modified. Failed extractions are not cached.
+// Without a cache every request is a miss, reported with an empty status.
+//
+// The shared extraction runs with the context of the request that started
+// it. A request whose context ends stops waiting with the context's error,
+// and one that was sharing the extraction of a canceled request runs its
+// own instead.
+func (s *Service) ExtractCached(ctx context.Context, text string, opts ExtractOptions, extract func(context.Context) (*ExtractResponse, error)) (*ExtractResponse, CacheStatus, error) {
+	key := s.cacheKey(text, opts)
+	status := CacheStatus("")
+	if s.cache != nil {
+		status = CacheMiss
+		if data, ok := s.cache.Get(key); ok {
+			var response ExtractResponse
+			if err := json.Unmarshal(data, &response); err == nil {
+				s.cacheCounters.hits.Add(1)
+				return &response, CacheHit, nil
+			}
+		}
+		s.cacheCounters.misses.Add(1)
+	}
+
+	var leader atomic.Bool
+	results := s.inflight.DoChan(key, func() (any, error) {
+		leader.Store(true)
+		response, err := extract(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.storeResult(key, response)
+		return response, nil
+	})
+
+	select {
+	case result := <-results:
+		if !leader.Load() {
+			s.cacheCounters.coalesced.Add(1)
+			if IsContextError(result.Err) && ctx.Err() == nil {
+				response, err := extract(ctx)
+				return response, status, err
+			}
+		}
+		if result.Err != nil {
+			return nil, status, result.Err
+		}
+		return result.Val.(*ExtractResponse), status, nil
+	case <-ctx.Done():
+		return nil, status, ctx.Err()
+	}
+}
+
+func (s *Service) storeResult(key string, response *ExtractResponse) {
+	if s.cache == nil {
+		return
+	}
+	data, err := json.Marshal(response)
+	if err == nil {
+		err = s.cache.Set(key, data)
+	}
+	if err != nil {
+		s.cacheCounters.errors.Add(1)
+	}
+}
+
+// cacheKey combines the fingerprint of the model and settings, the request
+// options and a hash of the text.
+func (s *Service) cacheKey(text string, opts ExtractOptions) string {
+	format := opts.Format
+	if format == "" {
+		format = "text"
+	}
+	types := "all"
+	if len(opts.EntityTypes) > 0 {
+		sorted := slices.Clone(opts.EntityTypes)
+		slices.Sort(sorted)
+		types = strings.Join(slices.Compact(sorted), ",")
+	}
+	sum := sha256.Sum256([]byte(text))
+	return s.current.Load().fingerprint + "/" + format + "/" + strconv.FormatBool(opts.Annotate) + "/" + types + "/" + hex.EncodeToString(sum[:])
+}
+
+func (s *Service) cacheStats() *CacheStats {
+	if s.cache == nil {
+		return nil
+	}
+	return &CacheStats{
+		Entries: s.cache.Len(),
+		Hits:    s.cacheCounters.hits.Load(),
+		Misses:  s.cacheCounters.misses.Load(),
+		Errors:  s.cacheCounters.errors.Load(),
+	}
+}
+
+// fingerprint identifies everything besides the request that determines a
+// result: the backend and the checksums of the model files, and the
+// tokenizer and normalization settings.
+func fingerprint(opts Options) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%+v\x00%q\x00", opts.Backend, opts.Tokenizer, opts.TokenizerOptions, opts.Normalization)
+	for _, path := range []string{opts.ModelPath, opts.TruecaseModelPath, opts.HandlesPath} {
+		if path == "" {
+			h.Write([]byte{0})
+			continue
+		}
+		if err := hashFile(h, path); err != nil {
+			return "", fmt.Errorf("failed to checksum %s: %w", path, err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,106 @@
+package ner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ner-service-go/internal/canary"
+	"ner-service-go/internal/pool"
+)
+
+// Component check statuses.
+const (
+	CheckOK     = "ok"
+	CheckFailed = "failed"
+)
+
+// canaryText is run through the loaded models by HealthCheck, which
+// expects canaryEntities in the result. Any model fit to serve requests
+// finds them.
+const canaryText = canary.Text
+
+var canaryEntities = []Entity{{Tag: "PERSON", Label: "María García"}, {Tag: "LOCATION", Label: "Madrid"}}
+
+// ComponentCheck is the outcome of checking one component of the Service.
+type ComponentCheck struct {
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// HealthCheck checks the components of the Service: the NER model and the
+// truecasing model, when one is loaded, by extracting entities from a
+// canary sentence, the backend pool and the result cache. Model checks wait
+// for a backend like any request, until ctx ends.
+func (s *Service) HealthCheck(ctx context.Context) []ComponentCheck {
+	checks := []ComponentCheck{
+		runCheck("model", func() error {
+			return s.checkCanary(ctx, canaryText)
+		}),
+	}
+	if s.truecaser != nil {
+		// All-capitals text is truecased before recognition
+		checks = append(checks, runCheck("truecase", func() error {
+			return s.checkCanary(ctx, strings.ToUpper(canaryText))
+		}))
+	}
+	checks = append(checks, runCheck("pool", func() error {
+		stats := s.current.Load().backends.Stats()
+		if stats.QueueCapacity > 0 && stats.QueueDepth >= stats.QueueCapacity {
+			return fmt.Errorf("%w: %d requests queued", pool.ErrQueueFull, stats.QueueDepth)
+		}
+		return nil
+	}))
+	if s.cache != nil {
+		checks = append(checks, runCheck("cache", s.checkCache))
+	}
+	return checks
+}
+
+func runCheck(name string, check func() error) ComponentCheck {
+	start := time.Now()
+	err := check()
+	result := ComponentCheck{Name: name, Status: CheckOK, DurationSeconds: time.Since(start).Seconds()}
+	if err != nil {
+		result.Status, result.Error = CheckFailed, err.Error()
+	}
+	return result
+}
+
+// checkCanary extracts entities from text, bypassing the result cache, and
+// checks that the canary entities are among them.
+func (s *Service) checkCanary(ctx context.Context, text string) error {
+	entities, err := s.ExtractEntities(ctx, text)
+	if err != nil {
+		return err
+	}
+	for _, expected := range canaryEntities {
+		found := false
+		for _, entity := range entities {
+			if entity.Tag == expected.Tag && strings.EqualFold(entity.Label, expected.Label) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("expected %s %q in %q, but got %d other entities", expected.Tag, expected.Label, text, len(entities))
+		}
+	}
+	return nil
+}
+
+// checkCache stores and reads back a value.
+func (s *Service) checkCache() error {
+	key := "healthcheck/" + s.current.Load().fingerprint
+	value := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+	if err := s.cache.Set(key, value); err != nil {
+		return err
+	}
+	if stored, ok := s.cache.Get(key); !ok || string(stored) != string(value) {
+		return fmt.Errorf("cache did not return the value just stored")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+//go:build cgo && !nomitie
+
+package ner
+
+import (
+	"fmt"
+
+	"github.com/sbl/ner"
+)
+
+// mitieBackend runs a MITIE named entity extractor through cgo.
+type mitieBackend struct {
+	extractor *ner.Extractor
+}
+
+func newMITIEBackend(modelPath string) (backend, error) {
+	extractor, err := ner.NewExtractor(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MITIE extractor: %w", err)
+	}
+	return &mitieBackend{extractor: extractor}, nil
+}
+
+func (b *mitieBackend) tokenize(text string) []string {
+	return ner.Tokenize(text)
+}
+
+func (b *mitieBackend) extract(tokens []string) ([]detection, error) {
+	entities, err := b.extractor.Extract(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]detection, len(entities))
+	for i, entity := range entities {
+		result[i] = detection{
+			tag:   mapTagToStandardFormat(entity.Tag),
+			score: entity.Score,
+			start: entity.Range.Start,
+			end:   entity.Range.End,
+		}
+	}
+	return result, nil
+}
+
+func (b *mitieBackend) close() {
+	if b.extractor != nil {
+		b.extractor.Free()
+	}
+}
+
+func mapTagToStandardFormat(mitieTag int) string {
+	switch mitieTag {
+	case 0:
+		return "LOCATION" // LOC
+	case 1:
+		return "ORGANIZATION" // ORG
+	case 2:
+		return "PERSON" // PER
+	case 3:
+		return "MISC" // MISC
+	default:
+		return "MISC" // Default to MISC
+	}
+}
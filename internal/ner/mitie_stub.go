@@ -0,0 +1,13 @@
+//go:build !cgo || nomitie
+
+package ner
+
+import "errors"
+
+// errMITIEUnavailable is returned when the binary was built without cgo, for
+// example as a static binary for the perceptron backend.
+var errMITIEUnavailable = errors.New("MITIE backend is not available in this build (requires cgo)")
+
+func newMITIEBackend(modelPath string) (backend, error) {
+	return nil, errMITIEUnavailable
+}
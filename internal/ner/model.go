@@ -0,0 +1,205 @@
+package ner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ner-service-go/internal/pool"
+	"ner-service-go/internal/tokenizer"
+)
+
+// ErrReloadInProgress is returned by Reload when another reload is running.
+var ErrReloadInProgress = errors.New("a model reload is already in progress")
+
+// ErrClosed is returned by Reload once Close was called.
+var ErrClosed = errors.New("the NER service is closed")
+
+// probeText is extracted with a reloaded model before it serves requests. A
+// working model finds at least one entity in it.
+var probeText = canaryText
+
+// ModelInfo describes the model serving requests.
+type ModelInfo struct {
+	Backend string `json:"backend"`
+	Path    string `json:"path"`
+	// Generation counts the models loaded, 1 for the model loaded at
+	// startup.
+	Generation int       `json:"generation"`
+	LoadedAt   time.Time `json:"loaded_at"`
+	// LoadSeconds is how long loading the model took.
+	LoadSeconds float64 `json:"load_seconds"`
+	// LastReloadError is why the last reload failed, if it did. The
+	// previous model kept serving requests.
+	LastReloadError string `json:"last_reload_error,omitempty"`
+}
+
+// model is a generation of backend instances loaded from the model file.
+// Requests hold a reference while they use it, and the model holds one for
+// itself while it is current, so a replaced model is freed once the
+// requests already using it, running or queued, are done.
+type model struct {
+	backends    *pool.Pool[backend]
+	fingerprint string
+	info        ModelInfo
+
+	refs     atomic.Int64
+	freeOnce sync.Once
+	freed    *sync.WaitGroup
+}
+
+func (m *model) tryAcquire() bool {
+	for {
+		n := m.refs.Load()
+		if n == 0 {
+			return false
+		}
+		if m.refs.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+func (m *model) release() {
+	if m.refs.Add(-1) == 0 {
+		m.free()
+	}
+}
+
+// free waits for busy backends, fails requests still queued with
+// pool.ErrClosed and closes the backends.
+func (m *model) free() {
+	m.freeOnce.Do(func() {
+		for _, b := range m.backends.Close() {
+			b.close()
+		}
+		m.freed.Done()
+	})
+}
+
+// loadModel creates the backend instances of a model generation.
+func (s *Service) loadModel(generation int) (*model, error) {
+	start := time.Now()
+	backends := make([]backend, 0, max(s.opts.PoolSize, 1))
+	for range cap(backends) {
+		b, err := newBackend(s.opts.Backend, s.opts.ModelPath)
+		if err != nil {
+			for _, created := range backends {
+				created.close()
+			}
+			return nil, err
+		}
+		backends = append(backends, b)
+	}
+
+	var fp string
+	if s.cache != nil {
+		var err error
+		if fp, err = fingerprint(s.opts); err != nil {
+			for _, b := range backends {
+				b.close()
+			}
+			return nil, err
+		}
+	}
+
+	m := &model{
+		backends:    pool.New(backends, s.opts.QueueSize),
+		fingerprint: fp,
+		info: ModelInfo{
+			Backend:     s.opts.Backend,
+			Path:        s.opts.ModelPath,
+			Generation:  generation,
+			LoadedAt:    time.Now(),
+			LoadSeconds: time.Since(start).Seconds(),
+		},
+		freed: &s.models,
+	}
+	m.refs.Store(1)
+	s.models.Add(1)
+	return m, nil
+}
+
+// acquireModel returns the current model, referenced until release.
+func (s *Service) acquireModel() *model {
+	for {
+		if m := s.current.Load(); m.tryAcquire() {
+			return m
+		}
+	}
+}
+
+// Reload loads the model file again and, once the new model extracts
+// entities from a probe sentence, swaps it in for new requests. Requests
+// already using the previous model finish with it, and it is freed after
+// them. When loading or the probe fails, the previous model keeps serving
+// requests and the error is returned. Both models are in memory while the
+// new one loads. It fails with ErrClosed once Close was called.
+func (s *Service) Reload(ctx context.Context) (ModelInfo, error) {
+	if !s.reloading.TryLock() {
+		return ModelInfo{}, ErrReloadInProgress
+	}
+	defer s.reloading.Unlock()
+	if s.closed {
+		return ModelInfo{}, ErrClosed
+	}
+	s.reloadInProgress.Store(true)
+	defer s.reloadInProgress.Store(false)
+
+	old := s.current.Load()
+	next, err := s.loadModel(old.info.Generation + 1)
+	if err == nil {
+		err = s.probe(ctx, next)
+		if err != nil {
+			next.release()
+		}
+	}
+	if err != nil {
+		s.lastReloadError.Store(err.Error())
+		return ModelInfo{}, err
+	}
+
+	s.lastReloadError.Store("")
+	s.current.Store(next)
+	old.release()
+	return next.info, nil
+}
+
+// probe checks that m finds entities in the probe sentence.
+func (s *Service) probe(ctx context.Context, m *model) error {
+	b, err := m.backends.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.backends.Release(b)
+
+	var tokens []tokenizer.Token
+	if s.tokenizer != nil {
+		tokens = s.tokenizer.Tokenize(probeText)
+	} else {
+		tokens = tokenizer.Align(probeText, b.tokenize(probeText))
+	}
+	detections, err := b.extract(tokenizer.Texts(tokens))
+	if err != nil {
+		return fmt.Errorf("probe extraction failed: %w", err)
+	}
+	if len(detections) == 0 {
+		return fmt.Errorf("probe extraction found no entities in %q", probeText)
+	}
+	return nil
+}
+
+// Reloading reports whether a new model is being loaded.
+func (s *Service) Reloading() bool {
+	return s.reloadInProgress.Load()
+}
+
+// Model describes the model serving requests.
+func (s *Service) Model() ModelInfo {
+	info := s.current.Load().info
+	info.LastReloadError, _ = s.lastReloadError.Load().(string)
+	return info
+}
@@ -0,0 +1,22 @@
+package ner
+
+import "time"
+
+// Extraction describes one run of a model over a text.
+type Extraction struct {
+	// Characters and Tokens measure the normalized input.
+	Characters int
+	Tokens     int
+	// TokenizeDuration and ExtractDuration split the time spent with the
+	// extractor. Texts without tokens are not passed to the model.
+	TokenizeDuration time.Duration
+	ExtractDuration  time.Duration
+	Entities         []Entity
+}
+
+// Observer is told about every extraction, for example to export metrics.
+// It is called from the requests' goroutines, so it must be safe for
+// concurrent use.
+type Observer interface {
+	ObserveExtraction(Extraction)
+}
@@ -1,67 +1,347 @@
 package ner
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
-	"github.com/sbl/ner"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+	"ner-service-go/internal/cache"
+	"ner-service-go/internal/config"
+	"ner-service-go/internal/docextract"
+	"ner-service-go/internal/normalize"
+	"ner-service-go/internal/pool"
+	"ner-service-go/internal/social"
+	"ner-service-go/internal/tokenizer"
+	"ner-service-go/internal/truecase"
 )
 
+// Tokenizer names accepted in Options.Tokenizer.
+const (
+	TokenizerSpanish = "spanish"
+	TokenizerNative  = "native"
+)
+
+// Options configures the backend a Service runs.
+type Options struct {
+	// Backend is BackendMITIE (the default) or BackendPerceptron.
+	Backend string
+	// ModelPath is the model file for the selected backend.
+	ModelPath string
+	// Tokenizer is TokenizerSpanish (the default) or TokenizerNative, which
+	// uses the backend's own tokenizer (ner.Tokenize for MITIE).
+	Tokenizer string
+	// TokenizerOptions configures the Spanish tokenizer.
+	TokenizerOptions tokenizer.Options
+	// Normalization lists the normalize steps applied before tokenization.
+	Normalization []string
+	// TruecaseModelPath is a truecasing model applied to all-capitals and
+	// all-lowercase text before tokenization. Empty disables truecasing.
+	TruecaseModelPath string
+	// PoolSize is the number of backend instances serving requests
+	// concurrently. Zero means one.
+	PoolSize int
+	// QueueSize is how many requests may wait for a free backend instance
+	// before extraction fails with pool.ErrQueueFull.
+	QueueSize int
+	// HandlesPath is a dictionary mapping social media handles to entities,
+	// used by ExtractSocial. Empty leaves handles unresolved.
+	HandlesPath string
+	// CacheBackend is cache.BackendNone (the default), cache.BackendMemory
+	// or cache.BackendBolt, the store used by ExtractCached.
+	CacheBackend string
+	// CachePath is the database file of the bolt cache.
+	CachePath string
+	// CacheSize is the most entries the cache holds.
+	CacheSize int
+	// CacheTTL is how long cached results live. Zero keeps them until
+	// evicted.
+	CacheTTL time.Duration
+	// Observer, when set, is told about every extraction.
+	Observer Observer
+}
+
+// OptionsFromConfig returns the Service options described by cfg.
+func OptionsFromConfig(cfg *config.Config) Options {
+	tokenizerOptions := tokenizer.DefaultOptions()
+	tokenizerOptions.SplitContractions = cfg.SplitContractions
+	tokenizerOptions.SplitClitics = cfg.SplitClitics
+
+	return Options{
+		Backend:           cfg.Backend,
+		ModelPath:         cfg.BackendModelPath(),
+		Tokenizer:         cfg.Tokenizer,
+		TokenizerOptions:  tokenizerOptions,
+		Normalization:     cfg.Normalization,
+		TruecaseModelPath: cfg.TruecaseModelPath,
+		HandlesPath:       cfg.HandlesPath,
+		PoolSize:          cfg.PoolSize,
+		QueueSize:         cfg.QueueSize,
+		CacheBackend:      cfg.CacheBackend,
+		CachePath:         cfg.CachePath,
+		CacheSize:         cfg.CacheSize,
+		CacheTTL:          cfg.CacheTTL,
+	}
+}
+
 type Service struct {
-	extractor *ner.Extractor
+	opts Options
+	// current is the model serving new requests, replaced by Reload
+	current atomic.Pointer[model]
+	// models counts the models not freed yet
+	models sync.WaitGroup
+	// reloading is held by Reload, and by Close to set closed
+	reloading        sync.Mutex
+	closed           bool
+	reloadInProgress atomic.Bool
+	lastReloadError  atomic.Value
+
+	tokenizer  *tokenizer.Tokenizer
+	normalizer *normalize.Pipeline
+	truecaser  *truecase.Model
+	handles    social.Handles
+
+	cache         cache.Cache
+	cacheCounters cacheCounters
+	// inflight coalesces identical concurrent ExtractCached calls
+	inflight singleflight.Group
 }
 
-func NewService(modelPath string) (*Service, error) {
-	extractor, err := ner.NewExtractor(modelPath)
+func NewService(opts Options) (*Service, error) {
+	var tok *tokenizer.Tokenizer
+	switch opts.Tokenizer {
+	case "", TokenizerSpanish:
+		tok = tokenizer.New(opts.TokenizerOptions)
+	case TokenizerNative:
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q", opts.Tokenizer)
+	}
+
+	normalizer, err := normalize.New(opts.Normalization)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create MITIE extractor: %w", err)
+		return nil, err
 	}
 
-	return &Service{
-		extractor: extractor,
-	}, nil
+	var truecaser *truecase.Model
+	if opts.TruecaseModelPath != "" {
+		truecaser, err = truecase.LoadFile(opts.TruecaseModelPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load truecasing model: %w", err)
+		}
+	}
+
+	var handles social.Handles
+	if opts.HandlesPath != "" {
+		handles, err = social.LoadHandles(opts.HandlesPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var resultCache cache.Cache
+	if opts.CacheBackend != "" && opts.CacheBackend != cache.BackendNone {
+		resultCache, err = cache.Open(cache.Options{
+			Backend: opts.CacheBackend,
+			Path:    opts.CachePath,
+			Size:    opts.CacheSize,
+			TTL:     opts.CacheTTL,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Service{
+		opts:       opts,
+		tokenizer:  tok,
+		normalizer: normalizer,
+		truecaser:  truecaser,
+		handles:    handles,
+		cache:      resultCache,
+	}
+	// Each backend instance is used by one request at a time, since MITIE
+	// extractors are not known to be safe for concurrent use
+	m, err := s.loadModel(1)
+	if err != nil {
+		if resultCache != nil {
+			resultCache.Close()
+		}
+		return nil, err
+	}
+	s.current.Store(m)
+	return s, nil
 }
 
+// Close waits for in-flight extractions to finish, including those still
+// using a model replaced by Reload, and frees the backends and the result
+// cache. Requests waiting for a backend fail with pool.ErrClosed. A reload
+// in progress is waited for, and later reloads fail with ErrClosed, so no
+// model is loaded after the current one is freed.
 func (s *Service) Close() {
-	if s.extractor != nil {
-		s.extractor.Free()
+	s.reloading.Lock()
+	s.closed = true
+	s.reloading.Unlock()
+
+	s.current.Load().free()
+	s.models.Wait()
+	if s.cache != nil {
+		s.cache.Close()
 	}
 }
 
-func (s *Service) ExtractEntities(text string) ([]Entity, error) {
-	tokens := ner.Tokenize(text)
+// Stats returns the state of the backend pool of the current model and the
+// result cache, the number of coalesced requests and the model serving
+// requests.
+func (s *Service) Stats() Stats {
+	return Stats{
+		Model:     s.Model(),
+		Pool:      s.PoolStats(),
+		Cache:     s.cacheStats(),
+		Coalesced: s.cacheCounters.coalesced.Load(),
+	}
+}
+
+// PoolStats returns the state of the extractor pool of the model serving
+// requests. Unlike Stats, it does not read the cache, so it is cheap enough
+// to call on every request.
+func (s *Service) PoolStats() pool.Stats {
+	return s.current.Load().backends.Stats()
+}
+
+// EstimatedWait returns how long a request of the given priority arriving
+// now would wait for a backend. The priority of a request is set on its
+// context with pool.WithPriority.
+func (s *Service) EstimatedWait(priority pool.Priority) time.Duration {
+	return s.current.Load().backends.EstimatedWait(priority)
+}
+
+// ExtractEntities normalizes, truecases and tokenizes text and runs the
+// backend on it. Entity offsets and labels always refer to the original text.
+// It gives up with the context's error when ctx ends while waiting for a
+// backend, or before the backend starts; a running backend is not
+// interrupted.
+func (s *Service) ExtractEntities(ctx context.Context, text string) (_ []Entity, err error) {
+	ctx, span := tracer.Start(ctx, "ner.extract_entities")
+	defer func() { endSpan(span, err) }()
+
+	_, normalizeSpan := tracer.Start(ctx, "ner.normalize")
+	normalized, offsets := s.normalizer.Normalize(text)
+	if s.truecaser != nil {
+		cased, caseOffsets := s.truecaser.Apply(normalized)
+		normalized, offsets = cased, offsets.Compose(caseOffsets)
+	}
+	characters := utf8.RuneCountInString(normalized)
+	normalizeSpan.SetAttributes(attribute.Int("ner.input.characters", characters))
+	normalizeSpan.End()
+
+	m := s.acquireModel()
+	defer m.release()
+	span.SetAttributes(attribute.Int("ner.model.generation", m.info.Generation))
+	_, waitSpan := tracer.Start(ctx, "ner.queue_wait", trace.WithAttributes(
+		attribute.Int("ner.pool.queue_depth", m.backends.Stats().QueueDepth),
+	))
+	b, err := m.backends.Acquire(ctx)
+	endSpan(waitSpan, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire extractor: %w", err)
+	}
+	defer m.backends.Release(b)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	_, tokenizeSpan := tracer.Start(ctx, "ner.tokenize")
+	start := time.Now()
+	tokens := s.tokenize(b, normalized)
+	observed := Extraction{
+		Characters:       characters,
+		Tokens:           len(tokens),
+		TokenizeDuration: time.Since(start),
+	}
+	tokenizeSpan.SetAttributes(attribute.Int("ner.input.tokens", len(tokens)))
+	tokenizeSpan.End()
 	if len(tokens) == 0 {
+		s.observe(observed)
 		return []Entity{}, nil
 	}
 
-	entities, err := s.extractor.Extract(tokens)
+	_, extractSpan := tracer.Start(ctx, "ner.extract", trace.WithAttributes(
+		attribute.String("ner.backend", s.opts.Backend),
+	))
+	start = time.Now()
+	detections, err := b.extract(tokenizer.Texts(tokens))
+	endSpan(extractSpan, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract entities: %w", err)
 	}
+	observed.ExtractDuration = time.Since(start)
 
-	result := make([]Entity, len(entities))
-	for i, entity := range entities {
+	// Map the detected tokens back to the original text
+	_, postprocessSpan := tracer.Start(ctx, "ner.postprocess")
+	defer postprocessSpan.End()
+	result := make([]Entity, len(detections))
+	for i, d := range detections {
+		start, end := offsets.Span(tokens[d.start].Start, tokens[d.end-1].End)
 		result[i] = Entity{
-			Tag:   mapTagToStandardFormat(entity.Tag),
-			Score: strconv.FormatFloat(entity.Score, 'f', 6, 64),
-			Label: entity.Name,
+			Tag:   d.tag,
+			Score: strconv.FormatFloat(d.score, 'f', 6, 64),
+			Label: text[start:end],
+			Start: start,
+			End:   end,
 		}
 	}
+	postprocessSpan.SetAttributes(attribute.Int("ner.entities", len(result)))
+	observed.Entities = result
+	s.observe(observed)
 
 	return result, nil
 }
 
-func mapTagToStandardFormat(mitieTag int) string {
-	switch mitieTag {
-	case 0:
-		return "LOCATION" // LOC
-	case 1:
-		return "ORGANIZATION" // ORG
-	case 2:
-		return "PERSON" // PER
-	case 3:
-		return "MISC" // MISC
-	default:
-		return "MISC" // Default to MISC
+func (s *Service) observe(e Extraction) {
+	if s.opts.Observer != nil {
+		s.opts.Observer.ObserveExtraction(e)
+	}
+}
+
+// ExtractDocument extracts entities from each page or paragraph of doc on
+// its own, so entities never span sections. Offsets refer to doc.Text. It
+// stops between sections once ctx ends.
+func (s *Service) ExtractDocument(ctx context.Context, doc *docextract.Document) ([]Entity, error) {
+	result := []Entity{}
+	for _, section := range doc.Sections {
+		entities, err := s.ExtractEntities(ctx, doc.Text[section.Start:section.End])
+		if err != nil {
+			return nil, err
+		}
+		for _, entity := range entities {
+			entity.Start += section.Start
+			entity.End += section.Start
+			entity.Page = section.Page
+			entity.Paragraph = section.Paragraph
+			result = append(result, entity)
+		}
 	}
+	return result, nil
+}
+
+// tokenize splits text with the configured tokenizer. Tokens from the
+// backend's native tokenizer are aligned to the text to recover offsets.
+func (s *Service) tokenize(b backend, text string) []tokenizer.Token {
+	if s.tokenizer != nil {
+		return s.tokenizer.Tokenize(text)
+	}
+	return tokenizer.Align(text, b.tokenize(text))
+}
+
+// IsContextError reports whether an extraction stopped because its context
+// was canceled or its deadline passed.
+func IsContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
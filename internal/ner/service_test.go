@@ -0,0 +1,675 @@
+package ner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"ner-service-go/internal/cache"
+	"ner-service-go/internal/docextract"
+	"ner-service-go/internal/normalize"
+	"ner-service-go/internal/perceptron"
+	"ner-service-go/internal/pool"
+	"ner-service-go/internal/social"
+	"ner-service-go/internal/testutil"
+	"ner-service-go/internal/truecase"
+)
+
+// newTestService returns a Service running a small perceptron model, so the
+// tests do not need MITIE or a downloaded model.
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	service, err := NewService(Options{
+		Backend:       BackendPerceptron,
+		ModelPath:     trainTestModel(t),
+		Normalization: normalize.DefaultSteps,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	t.Cleanup(service.Close)
+	return service
+}
+
+// trainTestModel trains a perceptron model on the shared Spanish training
+// data and returns its path.
+func trainTestModel(t *testing.T) string {
+	t.Helper()
+
+	sentences, err := perceptron.ReadCoNLL(strings.NewReader(testutil.SpanishTrainingData))
+	if err != nil {
+		t.Fatalf("Failed to read training data: %v", err)
+	}
+	model, err := perceptron.Train(sentences, perceptron.TrainOptions{Iterations: 10})
+	if err != nil {
+		t.Fatalf("Failed to train model: %v", err)
+	}
+	modelPath := filepath.Join(t.TempDir(), "perceptron.model")
+	if err := model.SaveFile(modelPath); err != nil {
+		t.Fatalf("Failed to save model: %v", err)
+	}
+	return modelPath
+}
+
+func TestService_PerceptronBackend(t *testing.T) {
+	service := newTestService(t)
+
+	entities, err := service.ExtractEntities(context.Background(), testutil.SpanishTestTexts.PersonLocation)
+	if err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+
+	expected := []Entity{
+		{Tag: "PERSON", Label: "María García"},
+		{Tag: "LOCATION", Label: "Madrid"},
+	}
+	if len(entities) != len(expected) {
+		t.Fatalf("Expected %d entities, but got %d: %+v", len(expected), len(entities), entities)
+	}
+	for i, entity := range entities {
+		if entity.Tag != expected[i].Tag || entity.Label != expected[i].Label {
+			t.Errorf("Entity %d: expected %s (%s), but got %s (%s)", i, expected[i].Label, expected[i].Tag, entity.Label, entity.Tag)
+		}
+		if entity.Score == "" {
+			t.Errorf("Entity %d: expected a score", i)
+		}
+	}
+}
+
+func TestService_Offsets(t *testing.T) {
+	service := newTestService(t)
+	text := testutil.SpanishTestTexts.Mixed
+
+	entities, err := service.ExtractEntities(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+	if len(entities) == 0 {
+		t.Fatal("Expected entities, but got none")
+	}
+
+	for _, entity := range entities {
+		if text[entity.Start:entity.End] != entity.Label {
+			t.Errorf("Entity %q has offsets [%d, %d) covering %q", entity.Label, entity.Start, entity.End, text[entity.Start:entity.End])
+		}
+	}
+}
+
+func TestService_NormalizedOffsets(t *testing.T) {
+	service := newTestService(t)
+
+	// NFD "García" and a no-break space before "Madrid"
+	text := "María Garci\u0301a vive en\u00a0Madrid"
+
+	entities, err := service.ExtractEntities(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+
+	expected := []string{"María Garci\u0301a", "Madrid"}
+	if len(entities) != len(expected) {
+		t.Fatalf("Expected %d entities, but got %d: %+v", len(expected), len(entities), entities)
+	}
+	for i, entity := range entities {
+		if entity.Label != expected[i] || text[entity.Start:entity.End] != expected[i] {
+			t.Errorf("Expected entity %q at its original offsets, but got %q at [%d, %d)", expected[i], entity.Label, entity.Start, entity.End)
+		}
+	}
+}
+
+func TestService_Truecasing(t *testing.T) {
+	service := newTestService(t)
+
+	// Use the training sentences as the casing corpus
+	sentences, err := perceptron.ReadCoNLL(strings.NewReader(testutil.SpanishTrainingData))
+	if err != nil {
+		t.Fatalf("Failed to read training data: %v", err)
+	}
+	var corpus strings.Builder
+	for _, sentence := range sentences {
+		corpus.WriteString(strings.Join(sentence.Tokens, " ") + "\n")
+	}
+	service.truecaser, err = truecase.Train(strings.NewReader(corpus.String()))
+	if err != nil {
+		t.Fatalf("Failed to train truecasing model: %v", err)
+	}
+
+	text := "MARÍA GARCÍA VIVE EN MADRID"
+	entities, err := service.ExtractEntities(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+
+	expected := []string{"MARÍA GARCÍA", "MADRID"}
+	if len(entities) != len(expected) {
+		t.Fatalf("Expected %d entities, but got %d: %+v", len(expected), len(entities), entities)
+	}
+	for i, entity := range entities {
+		if entity.Label != expected[i] || text[entity.Start:entity.End] != expected[i] {
+			t.Errorf("Expected entity %q as written in the input, but got %q at [%d, %d)", expected[i], entity.Label, entity.Start, entity.End)
+		}
+	}
+}
+
+func TestService_ExtractDocument(t *testing.T) {
+	service := newTestService(t)
+
+	doc, err := docextract.Extract("notas.html", []byte("<p>Trabajo en Microsoft España</p><p>María García vive en Madrid</p>"), "")
+	if err != nil {
+		t.Fatalf("Failed to extract document: %v", err)
+	}
+
+	entities, err := service.ExtractDocument(context.Background(), doc)
+	if err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+
+	found := false
+	for _, entity := range entities {
+		if doc.Text[entity.Start:entity.End] != entity.Label {
+			t.Errorf("Entity %q has offsets [%d, %d) covering %q", entity.Label, entity.Start, entity.End, doc.Text[entity.Start:entity.End])
+		}
+		if entity.Label == "Madrid" {
+			found = true
+			if entity.Paragraph != 2 {
+				t.Errorf("Expected Madrid in paragraph 2, but got %d", entity.Paragraph)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected Madrid among %+v", entities)
+	}
+}
+
+func TestNewService_UnknownTokenizer(t *testing.T) {
+	if _, err := NewService(Options{Backend: BackendPerceptron, Tokenizer: "whitespace"}); err == nil {
+		t.Error("Expected an error for an unknown tokenizer")
+	}
+}
+
+func TestService_EmptyText(t *testing.T) {
+	service := newTestService(t)
+
+	entities, err := service.ExtractEntities(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+	if len(entities) != 0 {
+		t.Errorf("Expected no entities, but got %+v", entities)
+	}
+}
+
+func TestNewService_UnknownBackend(t *testing.T) {
+	if _, err := NewService(Options{Backend: "spacy"}); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
+
+func TestMapLabelToStandardFormat(t *testing.T) {
+	tests := map[string]string{
+		"PER":  "PERSON",
+		"LOC":  "LOCATION",
+		"ORG":  "ORGANIZATION",
+		"MISC": "MISC",
+		"DATE": "MISC",
+	}
+
+	for label, expected := range tests {
+		if tag := mapLabelToStandardFormat(label); tag != expected {
+			t.Errorf("Expected %s for %s, but got %s", expected, label, tag)
+		}
+	}
+}
+
+func TestService_ExtractSocial(t *testing.T) {
+	service := newTestService(t)
+	service.handles = social.Handles{"telefonica": {Tag: "ORGANIZATION", Name: "Telefónica"}}
+
+	text := "#MaríaGarcía vive en Madrid y trabaja con @Telefonica \U0001F44D"
+	entities, err := service.ExtractSocial(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+
+	expected := []Entity{
+		{Tag: "HASHTAG", Label: "#MaríaGarcía"},
+		{Tag: "PERSON", Label: "MaríaGarcía"},
+		{Tag: "LOCATION", Label: "Madrid"},
+		{Tag: "MENTION", Label: "@Telefonica"},
+		{Tag: "ORGANIZATION", Label: "@Telefonica", Name: "Telefónica"},
+		{Tag: "EMOJI", Label: "\U0001F44D"},
+	}
+	if len(entities) != len(expected) {
+		t.Fatalf("Expected %d entities, but got %d: %+v", len(expected), len(entities), entities)
+	}
+	for i, entity := range entities {
+		if entity.Tag != expected[i].Tag || entity.Label != expected[i].Label || entity.Name != expected[i].Name {
+			t.Errorf("Expected %+v, but got %+v", expected[i], entity)
+		}
+		if text[entity.Start:entity.End] != entity.Label {
+			t.Errorf("Expected offsets of %q to point into the post, but got %q", entity.Label, text[entity.Start:entity.End])
+		}
+	}
+}
+
+func TestService_Pool(t *testing.T) {
+	service, err := NewService(Options{
+		Backend:   BackendPerceptron,
+		ModelPath: trainTestModel(t),
+		PoolSize:  2,
+		QueueSize: 8,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.ExtractEntities(context.Background(), testutil.SpanishTestTexts.PersonLocation); err != nil {
+				t.Errorf("Failed to extract entities: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := service.Stats().Pool
+	if stats.Size != 2 || stats.Busy != 0 || stats.Acquired != 8 {
+		t.Errorf("Expected 2 idle workers after 8 extractions, but got %+v", stats)
+	}
+	if poolStats := service.PoolStats(); poolStats.Size != 2 || poolStats.Acquired != 8 {
+		t.Errorf("Expected PoolStats to match the pool in Stats, but got %+v", poolStats)
+	}
+}
+
+func TestService_ExtractCached(t *testing.T) {
+	modelPath := trainTestModel(t)
+	service, err := NewService(Options{
+		Backend:      BackendPerceptron,
+		ModelPath:    modelPath,
+		CacheBackend: cache.BackendMemory,
+		CacheSize:    10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	calls := 0
+	extract := func(ctx context.Context) (*ExtractResponse, error) {
+		calls++
+		entities, err := service.ExtractEntities(ctx, testutil.SpanishTestTexts.PersonLocation)
+		return &ExtractResponse{Entities: entities}, err
+	}
+
+	text := testutil.SpanishTestTexts.PersonLocation
+	first, status, err := service.ExtractCached(context.Background(), text, ExtractOptions{}, extract)
+	if err != nil || status != CacheMiss {
+		t.Fatalf("Expected a miss, but got %q, %v", status, err)
+	}
+	second, status, err := service.ExtractCached(context.Background(), text, ExtractOptions{Format: "text"}, extract)
+	if err != nil || status != CacheHit {
+		t.Fatalf("Expected a hit for the same text and options, but got %q, %v", status, err)
+	}
+	if calls != 1 || len(second.Entities) != len(first.Entities) {
+		t.Errorf("Expected 1 extraction and the same %d entities, but got %d and %d entities", len(first.Entities), calls, len(second.Entities))
+	}
+
+	if _, status, _ := service.ExtractCached(context.Background(), text, ExtractOptions{Format: "social"}, extract); status != CacheMiss {
+		t.Errorf("Expected a miss for other options, but got %q", status)
+	}
+	locations := ExtractOptions{EntityTypes: []string{"LOCATION", "PERSON"}}
+	if _, status, _ := service.ExtractCached(context.Background(), text, locations, extract); status != CacheMiss {
+		t.Errorf("Expected a miss for other entity types, but got %q", status)
+	}
+	reordered := ExtractOptions{EntityTypes: []string{"PERSON", "LOCATION"}}
+	if _, status, _ := service.ExtractCached(context.Background(), text, reordered, extract); status != CacheHit {
+		t.Errorf("Expected a hit for the same entity types in another order, but got %q", status)
+	}
+
+	stats := service.Stats().Cache
+	if stats == nil || stats.Hits != 2 || stats.Misses != 3 || stats.Entries != 3 {
+		t.Errorf("Expected 2 hits, 3 misses and 3 entries, but got %+v", stats)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	opts := Options{Backend: BackendPerceptron, ModelPath: trainTestModel(t)}
+	before, err := fingerprint(opts)
+	if err != nil {
+		t.Fatalf("Failed to fingerprint: %v", err)
+	}
+
+	opts.Normalization = normalize.DefaultSteps
+	if after, _ := fingerprint(opts); after == before {
+		t.Errorf("Expected the fingerprint to change with the normalization steps")
+	}
+
+	opts.ModelPath = filepath.Join(t.TempDir(), "missing.model")
+	if _, err := fingerprint(opts); err == nil {
+		t.Errorf("Expected an error for a missing model file")
+	}
+}
+
+func TestService_Coalescing(t *testing.T) {
+	service := newTestService(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	extract := func(ctx context.Context) (*ExtractResponse, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return &ExtractResponse{Entities: []Entity{{Tag: "PERSON", Label: "María"}}}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan *ExtractResponse, 5)
+	call := func() {
+		defer wg.Done()
+		response, _, err := service.ExtractCached(context.Background(), "María", ExtractOptions{}, extract)
+		if err != nil {
+			t.Errorf("Failed to extract: %v", err)
+		}
+		results <- response
+	}
+
+	wg.Add(1)
+	go call()
+	<-started
+	for range 4 {
+		wg.Add(1)
+		go call()
+	}
+	// Let the identical requests join the one in flight
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 extraction for 5 identical requests, but got %d", calls.Load())
+	}
+	for response := range results {
+		if response == nil || len(response.Entities) != 1 {
+			t.Errorf("Expected every request to get the shared result, but got %+v", response)
+		}
+	}
+	if coalesced := service.Stats().Coalesced; coalesced != 4 {
+		t.Errorf("Expected 4 coalesced requests, but got %d", coalesced)
+	}
+}
+
+func TestService_ContextCanceled(t *testing.T) {
+	service := newTestService(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.ExtractEntities(ctx, testutil.SpanishTestTexts.PersonLocation); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, but got %v", err)
+	}
+	if stats := service.Stats().Pool; stats.Busy != 0 {
+		t.Errorf("Expected the extractor to be released, but %d are busy", stats.Busy)
+	}
+}
+
+func TestService_CoalescedLeaderCanceled(t *testing.T) {
+	service := newTestService(t)
+
+	started := make(chan struct{})
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderDone := make(chan error)
+	go func() {
+		_, _, err := service.ExtractCached(leaderCtx, "María", ExtractOptions{}, func(ctx context.Context) (*ExtractResponse, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		leaderDone <- err
+	}()
+	<-started
+
+	followerDone := make(chan error)
+	go func() {
+		_, _, err := service.ExtractCached(context.Background(), "María", ExtractOptions{}, func(ctx context.Context) (*ExtractResponse, error) {
+			return &ExtractResponse{Entities: []Entity{}}, nil
+		})
+		followerDone <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancelLeader()
+
+	if err := <-leaderDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the canceled request to fail with context.Canceled, but got %v", err)
+	}
+	if err := <-followerDone; err != nil {
+		t.Errorf("Expected the waiting request to extract on its own, but got %v", err)
+	}
+}
+
+func TestService_Reload(t *testing.T) {
+	service := newTestService(t)
+	modelPath := service.Model().Path
+
+	info, err := service.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if info.Generation != 2 || service.Model().Generation != 2 {
+		t.Errorf("Expected generation 2 after a reload, but got %d", info.Generation)
+	}
+
+	// A broken model file is rejected and the loaded model keeps serving
+	if err := os.WriteFile(modelPath, []byte("not a model"), 0o600); err != nil {
+		t.Fatalf("Failed to write model: %v", err)
+	}
+	if _, err := service.Reload(context.Background()); err == nil {
+		t.Fatalf("Expected reloading a broken model to fail")
+	}
+	if model := service.Model(); model.Generation != 2 || model.LastReloadError == "" {
+		t.Errorf("Expected generation 2 with the reload error, but got %+v", model)
+	}
+	entities, err := service.ExtractEntities(context.Background(), testutil.SpanishTestTexts.PersonLocation)
+	if err != nil || len(entities) == 0 {
+		t.Errorf("Expected the previous model to keep extracting entities, but got %v, %v", entities, err)
+	}
+}
+
+func TestService_ReloadProbeFails(t *testing.T) {
+	service := newTestService(t)
+
+	defer func(text string) { probeText = text }(probeText)
+	probeText = testutil.SpanishTestTexts.NoEntities
+	if _, err := service.Reload(context.Background()); err == nil || !strings.Contains(err.Error(), "no entities") {
+		t.Errorf("Expected the probe to fail, but got %v", err)
+	}
+	if generation := service.Model().Generation; generation != 1 {
+		t.Errorf("Expected the rollback to keep generation 1, but got %d", generation)
+	}
+}
+
+func TestService_ReloadKeepsOldModelForInflightRequests(t *testing.T) {
+	service := newTestService(t)
+
+	// A request that started before the reload holds the old model
+	old := service.acquireModel()
+	b, err := old.backends.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire backend: %v", err)
+	}
+
+	if _, err := service.Reload(context.Background()); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	// The only old backend is busy, so check with a canceled context that
+	// its pool is still open
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := old.backends.Acquire(canceled); errors.Is(err, pool.ErrClosed) {
+		t.Fatalf("Expected the old model to stay open while in use")
+	}
+	if _, err := service.ExtractEntities(context.Background(), testutil.SpanishTestTexts.PersonLocation); err != nil {
+		t.Errorf("Expected new requests to use the new model, but got %v", err)
+	}
+
+	old.backends.Release(b)
+	old.release()
+	if _, err := old.backends.Acquire(canceled); !errors.Is(err, pool.ErrClosed) {
+		t.Errorf("Expected the old model to be freed after its last request, but got %v", err)
+	}
+}
+
+func TestService_ReloadAfterClose(t *testing.T) {
+	service := newTestService(t)
+
+	closed := make(chan struct{})
+	go func() {
+		service.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected Close to return")
+	}
+
+	// A reload finishing after Close would load a model nothing frees
+	if _, err := service.Reload(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, but got %v", err)
+	}
+	if generation := service.Model().Generation; generation != 1 {
+		t.Errorf("Expected no model to be loaded after Close, but got generation %d", generation)
+	}
+}
+
+func TestService_HealthCheck(t *testing.T) {
+	service, err := NewService(Options{
+		Backend:      BackendPerceptron,
+		ModelPath:    trainTestModel(t),
+		CacheBackend: cache.BackendMemory,
+		CacheSize:    10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	checks := service.HealthCheck(context.Background())
+	var names []string
+	for _, check := range checks {
+		names = append(names, check.Name)
+		if check.Status != CheckOK {
+			t.Errorf("Expected the %s check to pass, but got %+v", check.Name, check)
+		}
+	}
+	if strings.Join(names, ",") != "model,pool,cache" {
+		t.Errorf("Expected the model, pool and cache checks, but got %v", names)
+	}
+}
+
+func TestService_HealthCheckCanaryMissing(t *testing.T) {
+	service := newTestService(t)
+
+	defer func(entities []Entity) { canaryEntities = entities }(canaryEntities)
+	canaryEntities = []Entity{{Tag: "ORGANIZATION", Label: "Naciones Unidas"}}
+	checks := service.HealthCheck(context.Background())
+	if checks[0].Name != "model" || checks[0].Status != CheckFailed || !strings.Contains(checks[0].Error, "Naciones Unidas") {
+		t.Errorf("Expected the model check to fail for the missing entity, but got %+v", checks[0])
+	}
+}
+
+type recordingObserver struct {
+	extractions []Extraction
+}
+
+func (o *recordingObserver) ObserveExtraction(e Extraction) {
+	o.extractions = append(o.extractions, e)
+}
+
+func TestService_Observer(t *testing.T) {
+	observer := &recordingObserver{}
+	service, err := NewService(Options{
+		Backend:   BackendPerceptron,
+		ModelPath: trainTestModel(t),
+		Observer:  observer,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	text := testutil.SpanishTestTexts.PersonLocation
+	entities, err := service.ExtractEntities(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+	if _, err := service.ExtractEntities(context.Background(), " "); err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+
+	if len(observer.extractions) != 2 {
+		t.Fatalf("Expected 2 observed extractions, but got %d", len(observer.extractions))
+	}
+	first := observer.extractions[0]
+	if first.Characters != len([]rune(text)) || first.Tokens == 0 || len(first.Entities) != len(entities) {
+		t.Errorf("Expected the size and entities of the text, but got %+v", first)
+	}
+	if first.ExtractDuration <= 0 {
+		t.Errorf("Expected the extraction to be timed, but got %v", first.ExtractDuration)
+	}
+	if empty := observer.extractions[1]; empty.Tokens != 0 || empty.ExtractDuration != 0 {
+		t.Errorf("Expected no tokens and no extraction for blank text, but got %+v", empty)
+	}
+}
+
+func TestService_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+
+	service := newTestService(t)
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	if _, err := service.ExtractEntities(ctx, testutil.SpanishTestTexts.PersonLocation); err != nil {
+		t.Fatalf("Failed to extract entities: %v", err)
+	}
+	parent.End()
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	extraction, ok := spans["ner.extract_entities"]
+	if !ok || extraction.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("Expected an extraction span in the request's trace, but got %v", spans)
+	}
+	for _, name := range []string{"ner.normalize", "ner.queue_wait", "ner.tokenize", "ner.extract", "ner.postprocess"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("Expected a %s span", name)
+			continue
+		}
+		if span.Parent().SpanID() != extraction.SpanContext().SpanID() {
+			t.Errorf("Expected the %s span to be a child of the extraction span", name)
+		}
+	}
+	for _, attr := range spans["ner.postprocess"].Attributes() {
+		if attr.Key == "ner.entities" && attr.Value.AsInt64() != 2 {
+			t.Errorf("Expected 2 entities on the postprocess span, but got %d", attr.Value.AsInt64())
+		}
+	}
+}
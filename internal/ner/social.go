@@ -0,0 +1,67 @@
+package ner
+
+import (
+	"context"
+	"sort"
+
+	"ner-service-go/internal/social"
+)
+
+// spanScore is the score reported for hashtags, mentions, emoji and
+// dictionary matches, which are certain.
+const spanScore = "1.000000"
+
+// ExtractSocial extracts entities from a social media post. Hashtags are
+// segmented into words before recognition ("#PedroSánchez" is read as
+// "Pedro Sánchez"), mentions found in the handle dictionary become entities
+// of the dictionary's type, and every hashtag, mention and emoji is also
+// returned as a HASHTAG, MENTION or EMOJI entity. Offsets and labels refer
+// to the original post.
+func (s *Service) ExtractSocial(ctx context.Context, text string) ([]Entity, error) {
+	post := social.Parse(text, s.handles)
+
+	entities, err := s.ExtractEntities(ctx, post.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Entity{}
+	for _, entity := range entities {
+		start, end := post.Offsets.Span(entity.Start, entity.End)
+		if resolvedMention(post.Spans, start, end) {
+			// The dictionary entry replaces whatever the model found there
+			continue
+		}
+		entity.Start, entity.End, entity.Label = start, end, text[start:end]
+		result = append(result, entity)
+	}
+
+	for _, span := range post.Spans {
+		label := text[span.Start:span.End]
+		result = append(result, Entity{Tag: span.Kind, Score: spanScore, Label: label, Start: span.Start, End: span.End})
+		if span.Entity != nil {
+			result = append(result, Entity{
+				Tag:   span.Entity.Tag,
+				Score: spanScore,
+				Label: label,
+				Start: span.Start,
+				End:   span.End,
+				Name:  span.Entity.Name,
+			})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Start < result[j].Start })
+	return result, nil
+}
+
+// resolvedMention reports whether [start, end) overlaps a mention found in
+// the handle dictionary.
+func resolvedMention(spans []social.Span, start, end int) bool {
+	for _, span := range spans {
+		if span.Entity != nil && start < span.End && span.Start < end {
+			return true
+		}
+	}
+	return false
+}
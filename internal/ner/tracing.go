@@ -0,0 +1,20 @@
+package ner
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the stages of an extraction as spans, children of the
+// span in the caller's context. Spans carry sizes and timings, never text.
+var tracer = otel.Tracer("ner-service-go/internal/ner")
+
+// endSpan ends span, marking it failed when err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
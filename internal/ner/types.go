@@ -1,15 +1,89 @@
 package ner
 
+import (
+	"ner-service-go/internal/htmltext"
+	"ner-service-go/internal/pool"
+)
+
 type Entity struct {
 	Tag   string `json:"tag"`
 	Score string `json:"score"`
 	Label string `json:"label"`
+	// Start and End are the byte offsets of the entity in the input text
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// DOM locates the entity in the text nodes of an HTML input
+	DOM []htmltext.Location `json:"dom,omitempty"`
+	// Page and Paragraph locate the entity in an uploaded document, when the
+	// document format provides them
+	Page      int `json:"page,omitempty"`
+	Paragraph int `json:"paragraph,omitempty"`
+	// Name is the entity a social media handle stands for, from the handle
+	// dictionary
+	Name string `json:"name,omitempty"`
 }
 
 type ExtractRequest struct {
 	Text string `json:"text"`
+	// Format is "text" (the default), "html" or "social"
+	Format string `json:"format,omitempty"`
+	// Annotate requests the HTML input back with entities wrapped in <span> tags
+	Annotate bool `json:"annotate,omitempty"`
+	// EntityTypes restricts the entities returned to these tags
+	EntityTypes []string `json:"entity_types,omitempty"`
 }
 
 type ExtractResponse struct {
 	Entities []Entity `json:"entities"`
+	// Text is the visible text that entity offsets refer to, for HTML input
+	Text string `json:"text,omitempty"`
+	// AnnotatedHTML is the HTML input with entities wrapped in <span> tags
+	AnnotatedHTML string `json:"annotated_html,omitempty"`
+}
+
+// ExtractOptions are the per-document options of a batch request.
+type ExtractOptions struct {
+	// Format is "text" (the default), "html" or "social"
+	Format string `json:"format,omitempty"`
+	// Annotate requests the HTML input back with entities wrapped in <span> tags
+	Annotate bool `json:"annotate,omitempty"`
+	// EntityTypes restricts the entities returned to these tags
+	EntityTypes []string `json:"entity_types,omitempty"`
+}
+
+// BatchDocument is one document of a batch request. ID is chosen by the
+// client and echoed in the result.
+type BatchDocument struct {
+	ID      string         `json:"id"`
+	Text    string         `json:"text"`
+	Options ExtractOptions `json:"options"`
+}
+
+type BatchRequest struct {
+	Documents []BatchDocument `json:"documents"`
+}
+
+// BatchResult is the outcome for one document: its entities, or the reason
+// it failed.
+type BatchResult struct {
+	ID string `json:"id"`
+	*ExtractResponse
+	Error string `json:"error,omitempty"`
+	// Cache is "hit" or "miss" when the result cache is enabled
+	Cache CacheStatus `json:"cache,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// Stats describes the load on a Service.
+type Stats struct {
+	Model ModelInfo  `json:"model"`
+	Pool  pool.Stats `json:"pool"`
+	// Cache is set when the result cache is enabled.
+	Cache *CacheStats `json:"cache,omitempty"`
+	// Coalesced counts requests that shared the result of an identical
+	// request already being extracted.
+	Coalesced uint64 `json:"coalesced"`
 }
@@ -0,0 +1,170 @@
+// Package normalize cleans up text before tokenization while keeping a map
+// from the normalized text back to the caller's original offsets.
+package normalize
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"ner-service-go/internal/offsetmap"
+)
+
+// Step names accepted by New.
+const (
+	StepNFC        = "nfc"
+	StepControl    = "control"
+	StepWhitespace = "whitespace"
+	StepQuotes     = "quotes"
+)
+
+// DefaultSteps is the pipeline used when none is configured.
+var DefaultSteps = []string{StepNFC, StepControl, StepWhitespace, StepQuotes}
+
+type step func(text string) (string, *offsetmap.Map)
+
+// Pipeline applies normalization steps in order.
+type Pipeline struct {
+	steps []step
+}
+
+// New returns a pipeline running the named steps in order. An empty list
+// returns a pipeline that leaves text unchanged.
+func New(names []string) (*Pipeline, error) {
+	p := &Pipeline{}
+	for _, name := range names {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case StepNFC:
+			p.steps = append(p.steps, nfc)
+		case StepControl:
+			p.steps = append(p.steps, removeControl)
+		case StepWhitespace:
+			p.steps = append(p.steps, foldWhitespace)
+		case StepQuotes:
+			p.steps = append(p.steps, unifyQuotes)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown normalization step %q", name)
+		}
+	}
+	return p, nil
+}
+
+// Normalize returns the normalized text and the map from its offsets back to
+// text. The map is nil when no step changed anything.
+func (p *Pipeline) Normalize(text string) (string, *offsetmap.Map) {
+	var m *offsetmap.Map
+	for _, s := range p.steps {
+		out, next := s(text)
+		if out == text {
+			continue
+		}
+		text = out
+		m = m.Compose(next)
+	}
+	return text, m
+}
+
+// nfc composes decomposed characters, such as the NFD text produced by
+// macOS, so "España" is always spelled with a single "ñ" code point.
+func nfc(text string) (string, *offsetmap.Map) {
+	if norm.NFC.IsNormalString(text) {
+		return text, nil
+	}
+
+	b := offsetmap.NewBuilder(len(text))
+	var it norm.Iter
+	it.InitString(norm.NFC, text)
+	for !it.Done() {
+		start := it.Pos()
+		segment := it.Next()
+		b.Write(string(segment), start, it.Pos())
+	}
+	return b.Finish(len(text))
+}
+
+// removeControl drops control and invisible formatting characters such as
+// zero-width spaces, soft hyphens, byte order marks and bidi marks. Tabs and
+// line breaks are kept.
+func removeControl(text string) (string, *offsetmap.Map) {
+	return mapRunes(text, func(r rune) (string, bool) {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return "", false
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == utf8.RuneError {
+			return "", true
+		}
+		return "", false
+	})
+}
+
+// foldWhitespace turns every run of horizontal whitespace, including
+// non-breaking spaces, into a single space and normalizes line endings to
+// "\n". Line breaks are kept since they mark paragraph boundaries.
+func foldWhitespace(text string) (string, *offsetmap.Map) {
+	b := offsetmap.NewBuilder(len(text))
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\r':
+			end := i + size
+			if end < len(text) && text[end] == '\n' {
+				end++
+			}
+			b.Write("\n", i, end)
+			i = end
+		case r != '\n' && unicode.IsSpace(r):
+			end := i + size
+			for end < len(text) {
+				next, nextSize := utf8.DecodeRuneInString(text[end:])
+				if next == '\n' || next == '\r' || !unicode.IsSpace(next) {
+					break
+				}
+				end += nextSize
+			}
+			b.Write(" ", i, end)
+			i = end
+		default:
+			b.Write(text[i:i+size], i, i+size)
+			i += size
+		}
+	}
+	return b.Finish(len(text))
+}
+
+// unifyQuotes replaces typographic single and double quotes with their ASCII
+// forms. Spanish guillemets are kept since the tokenizer handles them.
+func unifyQuotes(text string) (string, *offsetmap.Map) {
+	return mapRunes(text, func(r rune) (string, bool) {
+		switch r {
+		case '‘', '’', '‚', '‛', '′':
+			return "'", true
+		case '“', '”', '„', '‟', '″':
+			return "\"", true
+		}
+		return "", false
+	})
+}
+
+// mapRunes rewrites the runes for which replace reports true.
+func mapRunes(text string, replace func(r rune) (string, bool)) (string, *offsetmap.Map) {
+	b := offsetmap.NewBuilder(len(text))
+	changed := false
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if out, ok := replace(r); ok {
+			b.Write(out, i, i+size)
+			changed = true
+		} else {
+			b.Write(text[i:i+size], i, i+size)
+		}
+		i += size
+	}
+	if !changed {
+		return text, nil
+	}
+	return b.Finish(len(text))
+}
@@ -0,0 +1,85 @@
+package normalize
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPipeline_Normalize(t *testing.T) {
+	pipeline, err := New(DefaultSteps)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"NFD to NFC", "Espan\u0303a", "Espa\u00f1a"},
+		{"Non-breaking spaces", "Pedro\u00a0\u00a0S\u00e1nchez", "Pedro S\u00e1nchez"},
+		{"Zero-width and soft hyphen", "Tele\u200bf\u00f3\u00adnica", "Telef\u00f3nica"},
+		{"Smart quotes", "\u201cEl Pa\u00eds\u201d y \u2018ABC\u2019", "\"El Pa\u00eds\" y 'ABC'"},
+		{"Line endings", "Madrid\r\nSevilla", "Madrid\nSevilla"},
+		{"Unchanged", "Mar\u00eda Garc\u00eda vive en Madrid", "Mar\u00eda Garc\u00eda vive en Madrid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := pipeline.Normalize(tt.text)
+			if out != tt.expected {
+				t.Errorf("Expected %q, but got %q", tt.expected, out)
+			}
+		})
+	}
+}
+
+func TestPipeline_OffsetsPointIntoOriginal(t *testing.T) {
+	pipeline, err := New(DefaultSteps)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	// NFD "Espan\u0303a", a no-break space and a trailing zero-width space
+	original := "\u201cVive\u00a0en Espan\u0303a\u201d, dijo Mar\u00eda\u200b."
+	out, m := pipeline.Normalize(original)
+
+	tests := []struct {
+		word     string
+		expected string
+	}{
+		{"Espa\u00f1a", "Espan\u0303a"},
+		{"Mar\u00eda", "Mar\u00eda"},
+		{"Vive en", "Vive\u00a0en"},
+		{"\"Vive", "\u201cVive"},
+	}
+	for _, tt := range tests {
+		idx := strings.Index(out, tt.word)
+		if idx < 0 {
+			t.Fatalf("Expected %q in normalized text %q", tt.word, out)
+		}
+		start, end := m.Span(idx, idx+len(tt.word))
+		if original[start:end] != tt.expected {
+			t.Errorf("Expected %q to map back to %q, but got %q", tt.word, tt.expected, original[start:end])
+		}
+	}
+}
+
+func TestNew_UnknownStep(t *testing.T) {
+	if _, err := New([]string{"nfc", "lowercase"}); err == nil {
+		t.Error("Expected an error for an unknown step")
+	}
+}
+
+func TestNew_NoSteps(t *testing.T) {
+	pipeline, err := New(nil)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	text := "Espan\u0303a"
+	out, m := pipeline.Normalize(text)
+	if out != text || m != nil {
+		t.Errorf("Expected text to be unchanged with a nil map, but got %q", out)
+	}
+}
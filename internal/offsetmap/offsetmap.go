@@ -0,0 +1,115 @@
+// Package offsetmap tracks how byte offsets in a rewritten text map back to
+// the text it was derived from.
+package offsetmap
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Map maps byte offsets in a rewritten text to byte offsets in its source.
+// A nil *Map is the identity mapping.
+type Map struct {
+	// starts[i] and ends[i] are the source range that produced output byte i
+	starts    []int
+	ends      []int
+	sourceLen int
+}
+
+// Builder builds a rewritten text together with its Map.
+type Builder struct {
+	out    strings.Builder
+	starts []int
+	ends   []int
+}
+
+// NewBuilder returns a Builder expecting output of roughly size bytes.
+func NewBuilder(size int) *Builder {
+	b := &Builder{
+		starts: make([]int, 0, size),
+		ends:   make([]int, 0, size),
+	}
+	b.out.Grow(size)
+	return b
+}
+
+// Write appends s, which was produced from the source range
+// [sourceStart, sourceEnd). Writing an empty s drops that source range.
+func (b *Builder) Write(s string, sourceStart, sourceEnd int) {
+	b.out.WriteString(s)
+	for range len(s) {
+		b.starts = append(b.starts, sourceStart)
+		b.ends = append(b.ends, sourceEnd)
+	}
+}
+
+// Copy appends s, whose characters map one to one onto the source starting
+// at sourceStart. s is normally copied from the source unchanged, or is a
+// rewrite of it with the same layout, such as a change of case.
+func (b *Builder) Copy(s string, sourceStart int) {
+	for i := 0; i < len(s); {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.Write(s[i:i+size], sourceStart+i, sourceStart+i+size)
+		i += size
+	}
+}
+
+// Len returns the number of bytes written so far.
+func (b *Builder) Len() int {
+	return b.out.Len()
+}
+
+// Finish returns the rewritten text and its map onto a source of sourceLen
+// bytes.
+func (b *Builder) Finish(sourceLen int) (string, *Map) {
+	return b.out.String(), &Map{starts: b.starts, ends: b.ends, sourceLen: sourceLen}
+}
+
+// Span maps the output range [start, end) to the source range it came from.
+func (m *Map) Span(start, end int) (int, int) {
+	if m == nil {
+		return start, end
+	}
+	if end <= start {
+		pos := m.startOf(start)
+		return pos, pos
+	}
+	return m.startOf(start), m.endOf(end)
+}
+
+func (m *Map) startOf(pos int) int {
+	if pos >= len(m.starts) {
+		return m.sourceLen
+	}
+	return m.starts[pos]
+}
+
+func (m *Map) endOf(pos int) int {
+	if pos <= 0 {
+		return m.startOf(0)
+	}
+	if pos > len(m.ends) {
+		return m.sourceLen
+	}
+	return m.ends[pos-1]
+}
+
+// Compose returns the map from next's output to m's source, where next was
+// applied to m's output.
+func (m *Map) Compose(next *Map) *Map {
+	if m == nil {
+		return next
+	}
+	if next == nil {
+		return m
+	}
+	composed := &Map{
+		starts:    make([]int, len(next.starts)),
+		ends:      make([]int, len(next.ends)),
+		sourceLen: m.sourceLen,
+	}
+	for i := range next.starts {
+		composed.starts[i], composed.ends[i] = m.Span(next.starts[i], next.ends[i])
+	}
+	return composed
+}
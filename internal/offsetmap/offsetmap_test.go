@@ -0,0 +1,88 @@
+package offsetmap
+
+import "testing"
+
+func TestBuilder_Span(t *testing.T) {
+	// "a\u00a0\u200bb" becomes "a b": the no-break space is replaced and the
+	// zero-width space dropped
+	source := "a\u00a0\u200bb"
+	b := NewBuilder(len(source))
+	b.Write("a", 0, 1)
+	b.Write(" ", 1, 3)
+	b.Write("", 3, 6)
+	b.Write("b", 6, 7)
+	out, m := b.Finish(len(source))
+
+	if out != "a b" {
+		t.Fatalf("Expected output %q, but got %q", "a b", out)
+	}
+
+	tests := []struct {
+		start, end             int
+		sourceStart, sourceEnd int
+	}{
+		{0, 1, 0, 1},
+		{2, 3, 6, 7},
+		{0, 3, 0, 7},
+		{1, 2, 1, 3},
+		{3, 3, 7, 7},
+	}
+	for _, tt := range tests {
+		start, end := m.Span(tt.start, tt.end)
+		if start != tt.sourceStart || end != tt.sourceEnd {
+			t.Errorf("Span(%d, %d): expected [%d, %d), but got [%d, %d)", tt.start, tt.end, tt.sourceStart, tt.sourceEnd, start, end)
+		}
+	}
+}
+
+func TestMap_NilIsIdentity(t *testing.T) {
+	var m *Map
+	if start, end := m.Span(3, 8); start != 3 || end != 8 {
+		t.Errorf("Expected identity span [3, 8), but got [%d, %d)", start, end)
+	}
+}
+
+func TestMap_Compose(t *testing.T) {
+	// First step: "xxAB" -> "AB" (drop two bytes)
+	b1 := NewBuilder(4)
+	b1.Write("", 0, 2)
+	b1.Write("A", 2, 3)
+	b1.Write("B", 3, 4)
+	mid, m1 := b1.Finish(4)
+
+	// Second step: "AB" -> "aaB" (expand A)
+	b2 := NewBuilder(3)
+	b2.Write("aa", 0, 1)
+	b2.Write("B", 1, 2)
+	_, m2 := b2.Finish(len(mid))
+
+	composed := m1.Compose(m2)
+
+	if start, end := composed.Span(0, 2); start != 2 || end != 3 {
+		t.Errorf("Expected [2, 3), but got [%d, %d)", start, end)
+	}
+	if start, end := composed.Span(2, 3); start != 3 || end != 4 {
+		t.Errorf("Expected [3, 4), but got [%d, %d)", start, end)
+	}
+}
+
+func TestBuilder_Copy(t *testing.T) {
+	// "#año nuevo" becomes "año nuevo" with the copied text mapped character
+	// by character
+	source := "#año nuevo"
+	b := NewBuilder(len(source))
+	b.Copy(source[1:], 1)
+	out, m := b.Finish(len(source))
+
+	if out != "año nuevo" {
+		t.Fatalf("Expected output %q, but got %q", "año nuevo", out)
+	}
+	start, end := m.Span(5, 10)
+	if source[start:end] != "nuevo" {
+		t.Errorf("Expected %q, but got %q", "nuevo", source[start:end])
+	}
+	start, end = m.Span(0, 4)
+	if source[start:end] != "año" {
+		t.Errorf("Expected %q, but got %q", "año", source[start:end])
+	}
+}
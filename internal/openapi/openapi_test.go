@@ -0,0 +1,115 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Text string   `json:"text,omitempty"`
+	Tags []string `json:"tags"`
+}
+
+type result struct {
+	ID string `json:"id"`
+	*inner
+	Count   uint64         `json:"count"`
+	Seen    time.Time      `json:"seen"`
+	Labels  map[string]int `json:"labels,omitempty"`
+	Next    *result        `json:"next"`
+	private string
+	Skipped string `json:"-"`
+}
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("Failed to decode %s: %v", s, err)
+	}
+	return v
+}
+
+func TestDocument_SchemaOf(t *testing.T) {
+	d := New(Info{Title: "Test", Version: "1"})
+	ref := d.SchemaOf([]result{})
+	if ref.Type != "array" || ref.Items.Ref != "#/components/schemas/Result" {
+		t.Fatalf("Expected an array of Result, but got %+v", ref)
+	}
+
+	schema := d.Components.Schemas["Result"]
+	var names []string
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	for _, name := range []string{"id", "text", "tags", "count", "seen", "labels", "next"} {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("Expected the property %q, but got %v", name, names)
+		}
+	}
+	if len(schema.Properties) != 7 {
+		t.Errorf("Expected unexported and skipped fields to be left out, but got %v", names)
+	}
+	// Fields of an embedded pointer may be missing, omitempty fields too
+	if strings.Join(schema.Required, ",") != "id,count,seen,next" {
+		t.Errorf("Expected id, count, seen and next to be required, but got %v", schema.Required)
+	}
+	if schema.Properties["seen"].Format != "date-time" {
+		t.Errorf("Expected times to be date-time strings, but got %+v", schema.Properties["seen"])
+	}
+	if next := schema.Properties["next"]; len(next.OneOf) != 2 || next.OneOf[0].Ref != "#/components/schemas/Result" {
+		t.Errorf("Expected a nullable reference for the pointer, but got %+v", next)
+	}
+}
+
+func TestDocument_SchemaNameCollision(t *testing.T) {
+	type Result struct {
+		Other bool `json:"other"`
+	}
+	d := New(Info{Title: "Test", Version: "1"})
+	d.SchemaOf(result{})
+	if ref := d.SchemaOf(Result{}); ref.Ref != "#/components/schemas/OpenapiResult" {
+		t.Errorf("Expected the second Result to be qualified with its package, but got %q", ref.Ref)
+	}
+	if ref := d.SchemaOf(result{}); ref.Ref != "#/components/schemas/Result" {
+		t.Errorf("Expected the first type to keep its name, but got %q", ref.Ref)
+	}
+}
+
+func TestDocument_Validate(t *testing.T) {
+	d := New(Info{Title: "Test", Version: "1"})
+	schema := d.SchemaOf(result{})
+
+	valid := `{"id": "a", "tags": ["x"], "count": 2, "seen": "2026-01-01T00:00:00Z", "next": null, "labels": {"x": 1}}`
+	if err := d.Validate(schema, decode(t, valid)); err != nil {
+		t.Errorf("Expected a valid value, but got %v", err)
+	}
+
+	for name, value := range map[string]string{
+		"missing property":  `{"tags": [], "count": 2, "seen": "", "next": null}`,
+		"unknown property":  `{"id": "a", "count": 2, "seen": "", "next": null, "extra": 1}`,
+		"wrong type":        `{"id": 1, "count": 2, "seen": "", "next": null}`,
+		"negative unsigned": `{"id": "a", "count": -1, "seen": "", "next": null}`,
+		"fraction":          `{"id": "a", "count": 1.5, "seen": "", "next": null}`,
+		"null array":        `{"id": "a", "tags": null, "count": 1, "seen": "", "next": null}`,
+		"map value":         `{"id": "a", "count": 1, "seen": "", "next": null, "labels": {"x": "y"}}`,
+		"nested":            `{"id": "a", "count": 1, "seen": "", "next": {"id": "b"}}`,
+	} {
+		if err := d.Validate(schema, decode(t, value)); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestDocument_ValidateEnum(t *testing.T) {
+	d := New(Info{Title: "Test", Version: "1"})
+	schema := &Schema{Type: "string", Enum: []any{"ok", "failed"}}
+	if err := d.Validate(schema, "ok"); err != nil {
+		t.Errorf("Expected ok to be accepted, but got %v", err)
+	}
+	if err := d.Validate(schema, "maybe"); err == nil {
+		t.Errorf("Expected a value outside the enum to be rejected")
+	}
+}
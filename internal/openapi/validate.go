@@ -0,0 +1,132 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Validate checks that value, decoded from JSON into Go's generic types,
+// matches schema. References are resolved in the document's components.
+// Formats are not checked.
+func (d *Document) Validate(schema *Schema, value any) error {
+	return d.validate(schema, value, "$")
+}
+
+func (d *Document) validate(schema *Schema, value any, path string) error {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		resolved, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", path, schema.Ref)
+		}
+		return d.validate(resolved, value, path)
+	}
+
+	if len(schema.OneOf) > 0 {
+		matched := 0
+		var errs []string
+		for _, option := range schema.OneOf {
+			if err := d.validate(option, value, path); err != nil {
+				errs = append(errs, err.Error())
+			} else {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: expected exactly one schema of oneOf to match, but %d did (%s)", path, matched, strings.Join(errs, "; "))
+		}
+		return nil
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !slices.Contains(types, jsonType(value)) {
+		// Integers are numbers too
+		if !(jsonType(value) == "integer" && slices.Contains(types, "number")) {
+			return fmt.Errorf("%s: expected %s, but got %s", path, strings.Join(types, " or "), jsonType(value))
+		}
+	}
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, value) {
+		return fmt.Errorf("%s: expected one of %v, but got %v", path, schema.Enum, value)
+	}
+	if schema.Minimum != nil {
+		if n, ok := value.(float64); ok && n < *schema.Minimum {
+			return fmt.Errorf("%s: expected at least %v, but got %v", path, *schema.Minimum, n)
+		}
+	}
+
+	switch v := value.(type) {
+	case []any:
+		if schema.Items != nil {
+			for i, item := range v {
+				if err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			if !ok {
+				switch additional := schema.AdditionalProperties.(type) {
+				case bool:
+					if !additional {
+						return fmt.Errorf("%s: unexpected property %q", path, name)
+					}
+					continue
+				case *Schema:
+					property = additional
+				default:
+					continue
+				}
+			}
+			if err := d.validate(property, v[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func schemaTypes(schema *Schema) []string {
+	switch t := schema.Type.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	}
+	return nil
+}
+
+// jsonType returns the JSON Schema type of a value decoded by encoding/json.
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
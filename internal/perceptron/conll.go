@@ -0,0 +1,81 @@
+package perceptron
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Sentence is a tokenized training sentence with one BIO tag per token.
+type Sentence struct {
+	Tokens []string
+	Tags   []string
+}
+
+// ReadCoNLL parses training data in CoNLL format: one token per line with the
+// token in the first column and its IOB tag in the last column, and a blank
+// line between sentences. This is the layout of the CoNLL-2002 Spanish
+// corpus. IOB1 tags are converted to BIO so "I-PER" after "O" starts a new
+// entity.
+func ReadCoNLL(r io.Reader) ([]Sentence, error) {
+	var sentences []Sentence
+	var current Sentence
+
+	flush := func() {
+		if len(current.Tokens) > 0 {
+			current.Tags = toBIO(current.Tags)
+			sentences = append(sentences, current)
+		}
+		current = Sentence{}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			flush()
+			continue
+		}
+		if fields[0] == "-DOCSTART-" {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected token and tag columns", line)
+		}
+		tag := fields[len(fields)-1]
+		if tag != "O" && !strings.HasPrefix(tag, "B-") && !strings.HasPrefix(tag, "I-") {
+			return nil, fmt.Errorf("line %d: invalid tag %q", line, tag)
+		}
+		current.Tokens = append(current.Tokens, fields[0])
+		current.Tags = append(current.Tags, tag)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read training data: %w", err)
+	}
+	flush()
+
+	return sentences, nil
+}
+
+func toBIO(tags []string) []string {
+	prev := "O"
+	for i, tag := range tags {
+		if strings.HasPrefix(tag, "I-") && entityType(prev) != tag[2:] {
+			tags[i] = "B-" + tag[2:]
+		}
+		prev = tags[i]
+	}
+	return tags
+}
+
+// entityType returns the entity part of a BIO tag, or "" for "O".
+func entityType(tag string) string {
+	if len(tag) > 2 && tag[1] == '-' {
+		return tag[2:]
+	}
+	return ""
+}
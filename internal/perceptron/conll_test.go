@@ -0,0 +1,70 @@
+package perceptron
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadCoNLL(t *testing.T) {
+	data := `-DOCSTART- O
+
+María B-PER
+García I-PER
+vive O
+en O
+Madrid B-LOC
+
+Trabajo O
+en O
+Telefónica I-ORG
+`
+
+	sentences, err := ReadCoNLL(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to read CoNLL data: %v", err)
+	}
+
+	if len(sentences) != 2 {
+		t.Fatalf("Expected 2 sentences, but got %d", len(sentences))
+	}
+
+	expectedTags := []string{"B-PER", "I-PER", "O", "O", "B-LOC"}
+	for i, tag := range expectedTags {
+		if sentences[0].Tags[i] != tag {
+			t.Errorf("Expected tag %s at index %d, but got %s", tag, i, sentences[0].Tags[i])
+		}
+	}
+
+	// IOB1 "I-ORG" after "O" starts a new entity
+	if sentences[1].Tags[2] != "B-ORG" {
+		t.Errorf("Expected IOB1 tag to be converted to B-ORG, but got %s", sentences[1].Tags[2])
+	}
+}
+
+func TestReadCoNLL_InvalidTag(t *testing.T) {
+	_, err := ReadCoNLL(strings.NewReader("Madrid LOCATION\n"))
+	if err == nil {
+		t.Error("Expected an error for an invalid tag")
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"María García vive en Madrid.", []string{"María", "García", "vive", "en", "Madrid", "."}},
+		{"José María Álvarez-Pallete", []string{"José", "María", "Álvarez-Pallete"}},
+		{"¿Dónde está O'Donnell?", []string{"¿", "Dónde", "está", "O'Donnell", "?"}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			tokens := Tokenize(tt.text)
+			if strings.Join(tokens, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected tokens %q, but got %q", tt.expected, tokens)
+			}
+		})
+	}
+}
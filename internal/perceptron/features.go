@@ -0,0 +1,146 @@
+package perceptron
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sentenceContext holds the per-sentence data that does not depend on the
+// tags predicted so far, so it is computed once per sentence.
+type sentenceContext struct {
+	lower  []string
+	shapes []string
+	gaz    []string
+}
+
+func newSentenceContext(tokens []string, gazetteer *Gazetteer) *sentenceContext {
+	ctx := &sentenceContext{
+		lower:  make([]string, len(tokens)),
+		shapes: make([]string, len(tokens)),
+	}
+	for i, token := range tokens {
+		ctx.lower[i] = strings.ToLower(token)
+		ctx.shapes[i] = wordShape(token)
+	}
+	if gazetteer != nil {
+		ctx.gaz = gazetteer.Annotate(ctx.lower)
+	}
+	return ctx
+}
+
+func (c *sentenceContext) word(i int) string {
+	if i < 0 {
+		return "<s>"
+	}
+	if i >= len(c.lower) {
+		return "</s>"
+	}
+	return c.lower[i]
+}
+
+func (c *sentenceContext) shape(i int) string {
+	if i < 0 {
+		return "<s>"
+	}
+	if i >= len(c.shapes) {
+		return "</s>"
+	}
+	return c.shapes[i]
+}
+
+// features returns the feature strings for token i given the two previously
+// predicted tags.
+func (c *sentenceContext) features(tokens []string, i int, prev, prev2 string) []string {
+	word := c.lower[i]
+	token := tokens[i]
+
+	feats := make([]string, 0, 24)
+	feats = append(feats,
+		"bias",
+		"w="+word,
+		"shape="+c.shapes[i],
+		"p1="+prefix(word, 1),
+		"p2="+prefix(word, 2),
+		"p3="+prefix(word, 3),
+		"s1="+suffix(word, 1),
+		"s2="+suffix(word, 2),
+		"s3="+suffix(word, 3),
+		"w-1="+c.word(i-1),
+		"w+1="+c.word(i+1),
+		"w-2="+c.word(i-2),
+		"w+2="+c.word(i+2),
+		"shape-1="+c.shape(i-1),
+		"shape+1="+c.shape(i+1),
+		"t-1="+prev,
+		"t-2,t-1="+prev2+","+prev,
+		"t-1,w="+prev+","+word,
+		"t-1,shape="+prev+","+c.shapes[i],
+	)
+
+	if i == 0 {
+		feats = append(feats, "first")
+	}
+	if isTitle(token) {
+		feats = append(feats, "title")
+	}
+	if strings.ContainsRune(token, '-') {
+		feats = append(feats, "hyphen")
+	}
+	if c.gaz != nil && c.gaz[i] != "" {
+		feats = append(feats, "gaz="+c.gaz[i], "gaz,shape="+c.gaz[i]+","+c.shapes[i])
+	}
+
+	return feats
+}
+
+// wordShape maps a token to a compressed character class pattern, so that
+// "Madrid" becomes "Xx", "2020" becomes "d" and "Álvarez-Pallete" becomes
+// "Xx-Xx".
+func wordShape(token string) string {
+	var b strings.Builder
+	var last rune
+	for _, r := range token {
+		var class rune
+		switch {
+		case unicode.IsUpper(r):
+			class = 'X'
+		case unicode.IsLower(r):
+			class = 'x'
+		case unicode.IsDigit(r):
+			class = 'd'
+		default:
+			class = r
+		}
+		if class != last {
+			b.WriteRune(class)
+			last = class
+		}
+	}
+	return b.String()
+}
+
+func isTitle(token string) bool {
+	r, _ := utf8.DecodeRuneInString(token)
+	return unicode.IsUpper(r)
+}
+
+func prefix(word string, n int) string {
+	i := 0
+	for j := range word {
+		if i == n {
+			return word[:j]
+		}
+		i++
+	}
+	return word
+}
+
+func suffix(word string, n int) string {
+	i := len(word)
+	for k := 0; k < n && i > 0; k++ {
+		_, size := utf8.DecodeLastRuneInString(word[:i])
+		i -= size
+	}
+	return word[i:]
+}
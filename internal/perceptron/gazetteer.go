@@ -0,0 +1,81 @@
+package perceptron
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Gazetteer is a dictionary of known entity names used as tagger features.
+// Entries are stored as lowercased token sequences joined by single spaces.
+type Gazetteer struct {
+	Entries   map[string]string
+	MaxTokens int
+}
+
+// NewGazetteer returns an empty gazetteer.
+func NewGazetteer() *Gazetteer {
+	return &Gazetteer{Entries: make(map[string]string)}
+}
+
+// Add registers name under the given entity tag (e.g. "PER" or "LOC").
+func (g *Gazetteer) Add(tag, name string) {
+	tokens := Tokenize(strings.ToLower(name))
+	if len(tokens) == 0 {
+		return
+	}
+	g.Entries[strings.Join(tokens, " ")] = tag
+	if len(tokens) > g.MaxTokens {
+		g.MaxTokens = len(tokens)
+	}
+}
+
+// ReadGazetteer parses a gazetteer in "TAG<TAB>name" format, one entry per
+// line. Blank lines and lines starting with '#' are ignored.
+func ReadGazetteer(r io.Reader) (*Gazetteer, error) {
+	g := NewGazetteer()
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		tag, name, ok := strings.Cut(text, "\t")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("gazetteer line %d: expected TAG<TAB>name", line)
+		}
+		g.Add(strings.TrimSpace(tag), name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read gazetteer: %w", err)
+	}
+	return g, nil
+}
+
+// Annotate marks every token of lowered that is covered by a gazetteer entry
+// with "B-TAG" or "I-TAG", preferring the longest match at each position.
+// Tokens not covered by any entry are left empty.
+func (g *Gazetteer) Annotate(lowered []string) []string {
+	marks := make([]string, len(lowered))
+	for i := 0; i < len(lowered); {
+		matched := 0
+		for n := min(g.MaxTokens, len(lowered)-i); n > 0; n-- {
+			if tag, ok := g.Entries[strings.Join(lowered[i:i+n], " ")]; ok {
+				marks[i] = "B-" + tag
+				for j := i + 1; j < i+n; j++ {
+					marks[j] = "I-" + tag
+				}
+				matched = n
+				break
+			}
+		}
+		if matched == 0 {
+			matched = 1
+		}
+		i += matched
+	}
+	return marks
+}
@@ -0,0 +1,222 @@
+package perceptron
+
+import (
+	"errors"
+	"io"
+	"math"
+	"slices"
+	"strings"
+
+	"ner-service-go/internal/modelfile"
+)
+
+// modelMagic identifies perceptron model files. The trailing byte is the
+// format version.
+var modelMagic = []byte("NERPERC\x01")
+
+// ErrInvalidModel is returned when a file is not a perceptron model.
+var ErrInvalidModel = errors.New("not a perceptron model file")
+
+// Model is a trained averaged perceptron sequence tagger over BIO classes.
+type Model struct {
+	Classes   []string
+	Weights   map[string][]float64
+	Gazetteer *Gazetteer
+}
+
+// Span is an entity found by the model, given as a token range.
+type Span struct {
+	Start int
+	End   int
+	Tag   string
+	Score float64
+}
+
+// modelFile is the serialized form of a Model.
+type modelFile struct {
+	Classes   []string
+	Weights   map[string][]float32
+	Gazetteer map[string]string
+}
+
+// Tag predicts one BIO class per token using greedy left-to-right decoding.
+// It also returns the probability the model assigns to each chosen class.
+func (m *Model) Tag(tokens []string) ([]string, []float64) {
+	tags := make([]string, len(tokens))
+	probs := make([]float64, len(tokens))
+	ctx := newSentenceContext(tokens, m.Gazetteer)
+	scores := make([]float64, len(m.Classes))
+
+	prev, prev2 := "<s>", "<s>"
+	for i := range tokens {
+		m.score(ctx.features(tokens, i, prev, prev2), scores)
+		best := m.bestValid(scores, prev)
+		tags[i] = m.Classes[best]
+		probs[i] = m.probability(scores, prev, best)
+		prev2, prev = prev, tags[i]
+	}
+
+	return tags, probs
+}
+
+// Extract tags tokens and groups the BIO classes into entity spans. The score
+// of a span is the mean probability of its tokens' classes.
+func (m *Model) Extract(tokens []string) []Span {
+	tags, probs := m.Tag(tokens)
+
+	var spans []Span
+	for i := 0; i < len(tags); {
+		if !strings.HasPrefix(tags[i], "B-") {
+			i++
+			continue
+		}
+		span := Span{Start: i, Tag: tags[i][2:]}
+		total := probs[i]
+		j := i + 1
+		for ; j < len(tags) && tags[j] == "I-"+span.Tag; j++ {
+			total += probs[j]
+		}
+		span.End = j
+		span.Score = total / float64(j-i)
+		spans = append(spans, span)
+		i = j
+	}
+
+	return spans
+}
+
+func (m *Model) score(features []string, scores []float64) {
+	clear(scores)
+	for _, f := range features {
+		weights, ok := m.Weights[f]
+		if !ok {
+			continue
+		}
+		for c, w := range weights {
+			scores[c] += w
+		}
+	}
+}
+
+// validTransition reports whether class may follow prev in a BIO sequence.
+func validTransition(prev, class string) bool {
+	if !strings.HasPrefix(class, "I-") {
+		return true
+	}
+	return entityType(prev) == class[2:]
+}
+
+// bestValid returns the highest scoring class that may follow prev. "O" may
+// follow anything, so it is the fallback when no other class may.
+func (m *Model) bestValid(scores []float64, prev string) int {
+	best := -1
+	for c, class := range m.Classes {
+		if !validTransition(prev, class) {
+			continue
+		}
+		if best < 0 || scores[c] > scores[best] {
+			best = c
+		}
+	}
+	if best < 0 {
+		return slices.Index(m.Classes, "O")
+	}
+	return best
+}
+
+// probability is the softmax of the chosen class among the valid classes.
+func (m *Model) probability(scores []float64, prev string, chosen int) float64 {
+	var sum float64
+	for c, class := range m.Classes {
+		if validTransition(prev, class) {
+			sum += math.Exp(scores[c] - scores[chosen])
+		}
+	}
+	return 1 / sum
+}
+
+// Save writes the model in the perceptron model format: a magic header
+// followed by a gzip-compressed gob encoding.
+func (m *Model) Save(w io.Writer) error {
+	file := modelFile{
+		Classes: m.Classes,
+		Weights: make(map[string][]float32, len(m.Weights)),
+	}
+	for f, weights := range m.Weights {
+		compact := make([]float32, len(weights))
+		for i, w := range weights {
+			compact[i] = float32(w)
+		}
+		file.Weights[f] = compact
+	}
+	if m.Gazetteer != nil {
+		file.Gazetteer = m.Gazetteer.Entries
+	}
+
+	return modelfile.Write(w, modelMagic, &file)
+}
+
+// SaveFile writes the model to path.
+func (m *Model) SaveFile(path string) error {
+	return modelfile.WriteFile(path, m.Save)
+}
+
+// Load reads a model written by Save.
+func Load(r io.Reader) (*Model, error) {
+	var file modelFile
+	if err := modelfile.Read(r, modelMagic, &file); errors.Is(err, modelfile.ErrWrongMagic) {
+		return nil, ErrInvalidModel
+	} else if err != nil {
+		return nil, err
+	}
+	if !validClasses(file.Classes) {
+		return nil, ErrInvalidModel
+	}
+
+	m := &Model{
+		Classes: file.Classes,
+		Weights: make(map[string][]float64, len(file.Weights)),
+	}
+	for f, compact := range file.Weights {
+		// A weight vector of another length than the classes would index
+		// out of range when scoring
+		if len(compact) != len(file.Classes) {
+			return nil, ErrInvalidModel
+		}
+		weights := make([]float64, len(compact))
+		for i, w := range compact {
+			weights[i] = float64(w)
+		}
+		m.Weights[f] = weights
+	}
+	if len(file.Gazetteer) > 0 {
+		m.Gazetteer = NewGazetteer()
+		for name, tag := range file.Gazetteer {
+			m.Gazetteer.Entries[name] = tag
+			if n := strings.Count(name, " ") + 1; n > m.Gazetteer.MaxTokens {
+				m.Gazetteer.MaxTokens = n
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// validClasses reports whether classes can tag every sentence: they hold "O"
+// and a B- class for every I- class, so decoding always has a class to choose.
+func validClasses(classes []string) bool {
+	if !slices.Contains(classes, "O") {
+		return false
+	}
+	for _, class := range classes {
+		if strings.HasPrefix(class, "I-") && !slices.Contains(classes, "B-"+class[2:]) {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadFile reads a model from path.
+func LoadFile(path string) (*Model, error) {
+	return modelfile.ReadFile(path, Load)
+}
@@ -0,0 +1,194 @@
+package perceptron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const trainingData = `María B-PER
+García I-PER
+vive O
+en O
+Madrid B-LOC
+. O
+
+Pedro B-PER
+Sánchez I-PER
+visitó O
+Barcelona B-LOC
+. O
+
+Trabajo O
+en O
+Telefónica B-ORG
+. O
+
+Ana B-PER
+Martínez I-PER
+trabaja O
+en O
+Telefónica B-ORG
+en O
+Sevilla B-LOC
+. O
+`
+
+func trainTestModel(t *testing.T, gazetteer *Gazetteer) *Model {
+	t.Helper()
+
+	sentences, err := ReadCoNLL(strings.NewReader(trainingData))
+	if err != nil {
+		t.Fatalf("Failed to read training data: %v", err)
+	}
+
+	model, err := Train(sentences, TrainOptions{Iterations: 10, Gazetteer: gazetteer})
+	if err != nil {
+		t.Fatalf("Failed to train model: %v", err)
+	}
+	return model
+}
+
+func TestTrainAndExtract(t *testing.T) {
+	model := trainTestModel(t, nil)
+
+	spans := model.Extract(Tokenize("María García vive en Madrid."))
+
+	expected := []Span{
+		{Start: 0, End: 2, Tag: "PER"},
+		{Start: 4, End: 5, Tag: "LOC"},
+	}
+	if len(spans) != len(expected) {
+		t.Fatalf("Expected %d spans, but got %d: %+v", len(expected), len(spans), spans)
+	}
+	for i, span := range spans {
+		if span.Start != expected[i].Start || span.End != expected[i].End || span.Tag != expected[i].Tag {
+			t.Errorf("Span %d: expected %+v, but got %+v", i, expected[i], span)
+		}
+		if span.Score <= 0 || span.Score > 1 {
+			t.Errorf("Span %d: expected score in (0, 1], but got %f", i, span.Score)
+		}
+	}
+}
+
+func TestTrain_NoSentences(t *testing.T) {
+	if _, err := Train(nil, TrainOptions{}); err == nil {
+		t.Error("Expected an error when training without sentences")
+	}
+}
+
+func TestModel_SaveLoad(t *testing.T) {
+	gazetteer := NewGazetteer()
+	gazetteer.Add("LOC", "Santiago de Compostela")
+	model := trainTestModel(t, gazetteer)
+
+	var buf bytes.Buffer
+	if err := model.Save(&buf); err != nil {
+		t.Fatalf("Failed to save model: %v", err)
+	}
+
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Failed to load model: %v", err)
+	}
+
+	if strings.Join(loaded.Classes, ",") != strings.Join(model.Classes, ",") {
+		t.Errorf("Expected classes %v, but got %v", model.Classes, loaded.Classes)
+	}
+	if loaded.Gazetteer == nil || loaded.Gazetteer.MaxTokens != 3 {
+		t.Errorf("Expected gazetteer with 3-token entries to survive a round trip")
+	}
+
+	tokens := Tokenize("Pedro Sánchez visitó Barcelona.")
+	original, _ := model.Tag(tokens)
+	restored, _ := loaded.Tag(tokens)
+	if strings.Join(original, " ") != strings.Join(restored, " ") {
+		t.Errorf("Expected tags %v after loading, but got %v", original, restored)
+	}
+}
+
+func TestLoad_InvalidModel(t *testing.T) {
+	_, err := Load(strings.NewReader("this is a MITIE model"))
+	if err != ErrInvalidModel {
+		t.Errorf("Expected ErrInvalidModel, but got %v", err)
+	}
+}
+
+func TestLoad_MismatchedWeights(t *testing.T) {
+	model := &Model{
+		Classes: []string{"O", "B-PER", "I-PER"},
+		Weights: map[string][]float64{"w=pedro": {0, 1}},
+	}
+	var buf bytes.Buffer
+	if err := model.Save(&buf); err != nil {
+		t.Fatalf("Failed to save model: %v", err)
+	}
+
+	_, err := Load(&buf)
+	if err != ErrInvalidModel {
+		t.Errorf("Expected ErrInvalidModel, but got %v", err)
+	}
+}
+
+func TestLoad_InvalidClasses(t *testing.T) {
+	tests := map[string][]string{
+		"no classes":           nil,
+		"no outside class":     {"B-PER", "I-PER"},
+		"inside without begin": {"O", "B-PER", "I-PER", "I-LOC"},
+	}
+
+	for name, classes := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (&Model{Classes: classes}).Save(&buf); err != nil {
+				t.Fatalf("Failed to save model: %v", err)
+			}
+			if _, err := Load(&buf); err != ErrInvalidModel {
+				t.Errorf("Expected ErrInvalidModel, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestModel_TagFallsBackToOutside(t *testing.T) {
+	// I-PER scores highest but may not start a sentence or follow "O"
+	model := &Model{
+		Classes: []string{"I-PER", "O"},
+		Weights: map[string][]float64{"bias": {5, 1}},
+	}
+
+	tags, _ := model.Tag([]string{"Pedro", "Sánchez"})
+	if strings.Join(tags, " ") != "O O" {
+		t.Errorf("Expected tags [O O], but got %v", tags)
+	}
+}
+
+func TestGazetteer_Annotate(t *testing.T) {
+	gazetteer := NewGazetteer()
+	gazetteer.Add("LOC", "Santiago")
+	gazetteer.Add("LOC", "Santiago de Compostela")
+
+	marks := gazetteer.Annotate([]string{"en", "santiago", "de", "compostela"})
+
+	expected := []string{"", "B-LOC", "I-LOC", "I-LOC"}
+	for i, mark := range expected {
+		if marks[i] != mark {
+			t.Errorf("Expected mark %q at index %d, but got %q", mark, i, marks[i])
+		}
+	}
+}
+
+func TestWordShape(t *testing.T) {
+	tests := map[string]string{
+		"Madrid":          "Xx",
+		"2020":            "d",
+		"Álvarez-Pallete": "Xx-Xx",
+		"BBVA":            "X",
+	}
+
+	for token, expected := range tests {
+		if shape := wordShape(token); shape != expected {
+			t.Errorf("Expected shape %s for %s, but got %s", expected, token, shape)
+		}
+	}
+}
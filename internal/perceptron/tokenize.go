@@ -0,0 +1,46 @@
+package perceptron
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenize splits text into word and punctuation tokens. Runs of letters,
+// digits and inner hyphens or apostrophes form a single token; every other
+// non-space rune is a token on its own.
+func Tokenize(text string) []string {
+	tokens := make([]string, 0, len(text)/5+1)
+	start := -1
+
+	for i, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			if start < 0 {
+				start = i
+			}
+		case (r == '-' || r == '\'') && start >= 0 && nextIsWordRune(text, i+utf8.RuneLen(r)):
+			// Keep "Álvarez-Pallete" and "O'Donnell" together
+		default:
+			if start >= 0 {
+				tokens = append(tokens, text[start:i])
+				start = -1
+			}
+			if !unicode.IsSpace(r) {
+				tokens = append(tokens, string(r))
+			}
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, text[start:])
+	}
+
+	return tokens
+}
+
+func nextIsWordRune(text string, i int) bool {
+	if i >= len(text) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
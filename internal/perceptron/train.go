@@ -0,0 +1,155 @@
+package perceptron
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+)
+
+// TrainOptions controls perceptron training.
+type TrainOptions struct {
+	// Iterations is the number of passes over the training data.
+	Iterations int
+	// Seed makes the per-iteration shuffling reproducible.
+	Seed int64
+	// Gazetteer optionally adds dictionary features and is stored in the model.
+	Gazetteer *Gazetteer
+	// Progress, when set, is called after each iteration with the token
+	// accuracy achieved on the training data during that pass.
+	Progress func(iteration int, accuracy float64)
+}
+
+// trainer accumulates the averaged weights lazily: totals holds the sum of
+// each weight over all updates and stamps the update count at which a weight
+// last changed.
+type trainer struct {
+	model   *Model
+	classes map[string]int
+	totals  map[string][]float64
+	stamps  map[string][]int
+	updates int
+}
+
+// Train learns a model from BIO-tagged sentences.
+func Train(sentences []Sentence, opts TrainOptions) (*Model, error) {
+	if len(sentences) == 0 {
+		return nil, errors.New("no training sentences")
+	}
+	if opts.Iterations <= 0 {
+		opts.Iterations = 10
+	}
+
+	t := &trainer{
+		model: &Model{
+			Classes:   collectClasses(sentences),
+			Weights:   make(map[string][]float64),
+			Gazetteer: opts.Gazetteer,
+		},
+		classes: make(map[string]int),
+		totals:  make(map[string][]float64),
+		stamps:  make(map[string][]int),
+	}
+	for i, class := range t.model.Classes {
+		t.classes[class] = i
+	}
+
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	scores := make([]float64, len(t.model.Classes))
+
+	for iter := 1; iter <= opts.Iterations; iter++ {
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+
+		correct, total := 0, 0
+		for _, idx := range order {
+			s := sentences[idx]
+			ctx := newSentenceContext(s.Tokens, t.model.Gazetteer)
+			prev, prev2 := "<s>", "<s>"
+			for i := range s.Tokens {
+				features := ctx.features(s.Tokens, i, prev, prev2)
+				t.model.score(features, scores)
+				guess := t.model.bestValid(scores, prev)
+				truth := t.classes[s.Tags[i]]
+				t.update(features, truth, guess)
+				if guess == truth {
+					correct++
+				}
+				total++
+				prev2, prev = prev, t.model.Classes[guess]
+			}
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(iter, float64(correct)/float64(total))
+		}
+	}
+
+	t.average()
+	return t.model, nil
+}
+
+func (t *trainer) update(features []string, truth, guess int) {
+	t.updates++
+	if truth == guess {
+		return
+	}
+	for _, f := range features {
+		t.adjust(f, truth, 1)
+		t.adjust(f, guess, -1)
+	}
+}
+
+func (t *trainer) adjust(feature string, class int, delta float64) {
+	weights, ok := t.model.Weights[feature]
+	if !ok {
+		n := len(t.model.Classes)
+		weights = make([]float64, n)
+		t.model.Weights[feature] = weights
+		t.totals[feature] = make([]float64, n)
+		t.stamps[feature] = make([]int, n)
+	}
+	totals, stamps := t.totals[feature], t.stamps[feature]
+	totals[class] += float64(t.updates-stamps[class]) * weights[class]
+	stamps[class] = t.updates
+	weights[class] += delta
+}
+
+// average replaces every weight by its mean over all updates, which makes the
+// model far less sensitive to the order of the last training examples.
+func (t *trainer) average() {
+	for feature, weights := range t.model.Weights {
+		totals, stamps := t.totals[feature], t.stamps[feature]
+		nonZero := false
+		for c := range weights {
+			total := totals[c] + float64(t.updates-stamps[c])*weights[c]
+			weights[c] = total / float64(t.updates)
+			if weights[c] != 0 {
+				nonZero = true
+			}
+		}
+		if !nonZero {
+			delete(t.model.Weights, feature)
+		}
+	}
+}
+
+// collectClasses returns the BIO classes found in the data with "O" first.
+func collectClasses(sentences []Sentence) []string {
+	seen := map[string]bool{"O": true}
+	for _, s := range sentences {
+		for _, tag := range s.Tags {
+			seen[tag] = true
+		}
+	}
+	classes := make([]string, 0, len(seen))
+	for class := range seen {
+		if class != "O" {
+			classes = append(classes, class)
+		}
+	}
+	sort.Strings(classes)
+	return append([]string{"O"}, classes...)
+}
@@ -0,0 +1,348 @@
+// Package pool hands out a fixed set of workers to concurrent callers through
+// a bounded queue, first-come, first-served within each priority class, and
+// tracks how busy they are.
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned by Acquire when every worker is busy and the
+	// queue is at capacity.
+	ErrQueueFull = errors.New("worker queue is full")
+	// ErrClosed is returned by Acquire once the pool is closed.
+	ErrClosed = errors.New("worker pool is closed")
+)
+
+// Priority orders waiting callers: every high-priority caller is handed a
+// worker before any low-priority one.
+type Priority int
+
+const (
+	PriorityHigh Priority = iota
+	PriorityLow
+	numPriorities
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose Acquire calls wait in the queue of
+// the given priority. Callers without one are high priority.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityFrom(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok && priority >= 0 && priority < numPriorities {
+		return priority
+	}
+	return PriorityHigh
+}
+
+// throughputWindow is the period over which recent throughput is measured,
+// in one-second buckets.
+const throughputWindow = 10
+
+// Stats describes the state of a pool.
+type Stats struct {
+	// Size is the number of workers and Busy how many are in use.
+	Size int `json:"size"`
+	Busy int `json:"busy"`
+	// QueueDepth is the number of callers waiting for a worker, up to
+	// QueueCapacity.
+	QueueDepth    int `json:"queue_depth"`
+	QueueCapacity int `json:"queue_capacity"`
+	// QueueDepthHigh and QueueDepthLow split QueueDepth by priority.
+	QueueDepthHigh int `json:"queue_depth_high"`
+	QueueDepthLow  int `json:"queue_depth_low"`
+	// Acquired and Rejected count successful and refused Acquire calls.
+	Acquired uint64 `json:"acquired"`
+	Rejected uint64 `json:"rejected"`
+	// TotalWaitSeconds is the time callers spent queued, and
+	// AverageWaitSeconds that time per acquired worker.
+	TotalWaitSeconds   float64 `json:"total_wait_seconds"`
+	AverageWaitSeconds float64 `json:"average_wait_seconds"`
+	// Utilization is the fraction of worker time spent busy since the pool
+	// was created.
+	Utilization float64 `json:"utilization"`
+	// Throughput is the number of workers released per second over the last
+	// ten seconds.
+	Throughput float64 `json:"throughput"`
+}
+
+type waiter[T any] struct {
+	ch chan T
+}
+
+// Pool is a fixed set of workers of type T.
+type Pool[T any] struct {
+	mu       sync.Mutex
+	returned *sync.Cond
+	idle     []T
+	waiters  [numPriorities][]*waiter[T]
+	size     int
+	maxQueue int
+	closed   bool
+
+	created    time.Time
+	lastChange time.Time
+	busyTime   time.Duration
+	totalWait  time.Duration
+	acquired   uint64
+	rejected   uint64
+
+	// released counts releases per second, indexed by Unix second modulo
+	// throughputWindow, up to releasedSecond.
+	released       [throughputWindow]uint64
+	releasedSecond int64
+}
+
+// New returns a pool of the given workers that queues at most maxQueue
+// callers while all of them are busy.
+func New[T any](workers []T, maxQueue int) *Pool[T] {
+	now := time.Now()
+	p := &Pool[T]{
+		idle:       append([]T(nil), workers...),
+		size:       len(workers),
+		maxQueue:   maxQueue,
+		created:    now,
+		lastChange: now,
+	}
+	p.returned = sync.NewCond(&p.mu)
+	return p
+}
+
+// Acquire returns an idle worker, waiting in line for one when all are busy,
+// behind every caller of the same or higher priority (see WithPriority).
+// It fails with ErrQueueFull when the queue is at capacity and with the
+// context's error when ctx ends first. Every worker acquired must be given
+// back with Release.
+func (p *Pool[T]) Acquire(ctx context.Context) (T, error) {
+	var zero T
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return zero, ErrClosed
+	}
+	if len(p.idle) > 0 {
+		worker := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.markBusy()
+		p.acquired++
+		p.mu.Unlock()
+		return worker, nil
+	}
+	if p.queued() >= p.maxQueue {
+		p.rejected++
+		p.mu.Unlock()
+		return zero, ErrQueueFull
+	}
+	w := &waiter[T]{ch: make(chan T, 1)}
+	priority := priorityFrom(ctx)
+	p.waiters[priority] = append(p.waiters[priority], w)
+	p.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case worker, ok := <-w.ch:
+		if !ok {
+			return zero, ErrClosed
+		}
+		p.mu.Lock()
+		p.totalWait += time.Since(start)
+		p.mu.Unlock()
+		return worker, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		handed := !p.removeWaiter(priority, w)
+		p.mu.Unlock()
+		if handed {
+			// Release picked this caller just as ctx ended
+			if worker, ok := <-w.ch; ok {
+				p.Release(worker)
+			}
+		}
+		return zero, ctx.Err()
+	}
+}
+
+// Release gives a worker back, handing it to the longest-waiting caller of
+// the highest priority if there is one.
+func (p *Pool[T]) Release(worker T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.countRelease(time.Now())
+	for priority, waiters := range p.waiters {
+		if len(waiters) > 0 && !p.closed {
+			p.waiters[priority] = waiters[1:]
+			p.acquired++
+			waiters[0].ch <- worker
+			return
+		}
+	}
+	p.idle = append(p.idle, worker)
+	p.markIdle()
+	p.returned.Broadcast()
+}
+
+// Close stops handing out workers, fails waiting callers with ErrClosed,
+// waits until every busy worker has been released and returns all workers
+// so the caller can free them.
+func (p *Pool[T]) Close() []T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for priority, waiters := range p.waiters {
+		for _, w := range waiters {
+			close(w.ch)
+		}
+		p.waiters[priority] = nil
+	}
+	for len(p.idle) < p.size {
+		p.returned.Wait()
+	}
+	workers := p.idle
+	p.idle = nil
+	return workers
+}
+
+// Stats returns the current state of the pool.
+func (p *Pool[T]) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.accountBusyTime()
+	stats := Stats{
+		Size:             p.size,
+		Busy:             p.busy(),
+		QueueDepth:       p.queued(),
+		QueueCapacity:    p.maxQueue,
+		QueueDepthHigh:   len(p.waiters[PriorityHigh]),
+		QueueDepthLow:    len(p.waiters[PriorityLow]),
+		Acquired:         p.acquired,
+		Rejected:         p.rejected,
+		TotalWaitSeconds: p.totalWait.Seconds(),
+		Throughput:       p.throughput(time.Now()),
+	}
+	if p.acquired > 0 {
+		stats.AverageWaitSeconds = p.totalWait.Seconds() / float64(p.acquired)
+	}
+	if elapsed := time.Since(p.created); elapsed > 0 && p.size > 0 {
+		stats.Utilization = p.busyTime.Seconds() / (elapsed.Seconds() * float64(p.size))
+	}
+	return stats
+}
+
+// EstimatedWait returns how long a caller of the given priority arriving now
+// would wait for a worker, from the callers queued ahead of it and the
+// recent throughput, or from the average time workers are held when there
+// has been no recent release. It is zero when a worker is idle, and when
+// there is nothing to estimate from yet.
+func (p *Pool[T]) EstimatedWait(priority Priority) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ahead := 0
+	for higher := PriorityHigh; higher <= priority && higher < numPriorities; higher++ {
+		ahead += len(p.waiters[higher])
+	}
+	if len(p.idle) > 0 && ahead == 0 {
+		return 0
+	}
+
+	rate := p.throughput(time.Now())
+	if rate == 0 && p.acquired > 0 {
+		p.accountBusyTime()
+		if hold := p.busyTime.Seconds() / float64(p.acquired); hold > 0 {
+			rate = float64(p.size) / hold
+		}
+	}
+	if rate == 0 {
+		return 0
+	}
+	return time.Duration(float64(ahead+1) / rate * float64(time.Second))
+}
+
+func (p *Pool[T]) busy() int {
+	return p.size - len(p.idle)
+}
+
+// markBusy and markIdle record a worker changing state, after the idle list
+// has been updated.
+func (p *Pool[T]) markBusy() {
+	p.accountBusyTimeWith(p.busy() - 1)
+}
+
+func (p *Pool[T]) markIdle() {
+	p.accountBusyTimeWith(p.busy() + 1)
+}
+
+func (p *Pool[T]) accountBusyTime() {
+	p.accountBusyTimeWith(p.busy())
+}
+
+// accountBusyTimeWith adds the worker time spent busy since the last change,
+// during which busy workers were in use.
+func (p *Pool[T]) accountBusyTimeWith(busy int) {
+	now := time.Now()
+	p.busyTime += time.Duration(busy) * now.Sub(p.lastChange)
+	p.lastChange = now
+}
+
+// removeWaiter takes w out of its queue, reporting false if Release already
+// took it out to hand it a worker.
+func (p *Pool[T]) removeWaiter(priority Priority, w *waiter[T]) bool {
+	for i, queued := range p.waiters[priority] {
+		if queued == w {
+			p.waiters[priority] = append(p.waiters[priority][:i], p.waiters[priority][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Pool[T]) queued() int {
+	n := 0
+	for _, waiters := range p.waiters {
+		n += len(waiters)
+	}
+	return n
+}
+
+// countRelease records a release in the bucket of the current second,
+// clearing buckets of the seconds since the last one.
+func (p *Pool[T]) countRelease(now time.Time) {
+	p.advanceReleases(now)
+	p.released[now.Unix()%throughputWindow]++
+}
+
+func (p *Pool[T]) advanceReleases(now time.Time) {
+	second := now.Unix()
+	if second-p.releasedSecond >= throughputWindow {
+		p.released = [throughputWindow]uint64{}
+	} else {
+		for s := p.releasedSecond + 1; s <= second; s++ {
+			p.released[s%throughputWindow] = 0
+		}
+	}
+	p.releasedSecond = max(p.releasedSecond, second)
+}
+
+// throughput returns releases per second over the last throughputWindow
+// seconds, or since the pool was created if that is more recent.
+func (p *Pool[T]) throughput(now time.Time) float64 {
+	p.advanceReleases(now)
+	var total uint64
+	for _, n := range p.released {
+		total += n
+	}
+	window := min(now.Sub(p.created).Seconds(), throughputWindow)
+	return float64(total) / max(window, 1)
+}
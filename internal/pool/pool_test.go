@@ -0,0 +1,195 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPool_AcquireRelease(t *testing.T) {
+	p := New([]int{1, 2}, 0)
+
+	a, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	b, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if a == b {
+		t.Errorf("Expected distinct workers, but got %d twice", a)
+	}
+
+	if _, err := p.Acquire(context.Background()); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull with no queue, but got %v", err)
+	}
+
+	stats := p.Stats()
+	if stats.Busy != 2 || stats.Acquired != 2 || stats.Rejected != 1 {
+		t.Errorf("Expected 2 busy, 2 acquired and 1 rejected, but got %+v", stats)
+	}
+
+	p.Release(a)
+	p.Release(b)
+	if stats := p.Stats(); stats.Busy != 0 {
+		t.Errorf("Expected no busy workers, but got %d", stats.Busy)
+	}
+}
+
+func TestPool_QueueHandsOffInOrder(t *testing.T) {
+	p := New([]int{1}, 2)
+	worker, _ := p.Acquire(context.Background())
+
+	order := make(chan int, 2)
+	for i := 1; i <= 2; i++ {
+		go func(i int) {
+			w, err := p.Acquire(context.Background())
+			if err != nil {
+				t.Errorf("Acquire failed: %v", err)
+				return
+			}
+			order <- i
+			p.Release(w)
+		}(i)
+		waitForQueue(t, p, i)
+	}
+
+	if _, err := p.Acquire(context.Background()); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull with a full queue, but got %v", err)
+	}
+
+	p.Release(worker)
+	if first, second := <-order, <-order; first != 1 || second != 2 {
+		t.Errorf("Expected waiters served in order 1, 2, but got %d, %d", first, second)
+	}
+
+	stats := p.Stats()
+	if stats.QueueDepth != 0 || stats.Acquired != 3 || stats.TotalWaitSeconds <= 0 {
+		t.Errorf("Expected an empty queue, 3 acquisitions and some wait time, but got %+v", stats)
+	}
+}
+
+func TestPool_ContextCancel(t *testing.T) {
+	p := New([]int{1}, 1)
+	worker, _ := p.Acquire(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, but got %v", err)
+	}
+	if depth := p.Stats().QueueDepth; depth != 0 {
+		t.Errorf("Expected the canceled caller to leave the queue, but depth is %d", depth)
+	}
+
+	p.Release(worker)
+	if _, err := p.Acquire(context.Background()); err != nil {
+		t.Errorf("Expected the worker to be available again, but got %v", err)
+	}
+}
+
+func TestPool_Close(t *testing.T) {
+	p := New([]int{1, 2}, 1)
+	worker, _ := p.Acquire(context.Background())
+
+	done := make(chan []int)
+	go func() { done <- p.Close() }()
+
+	select {
+	case <-done:
+		t.Fatal("Expected Close to wait for the busy worker")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	p.Release(worker)
+	if workers := <-done; len(workers) != 2 {
+		t.Errorf("Expected Close to return 2 workers, but got %v", workers)
+	}
+	if _, err := p.Acquire(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, but got %v", err)
+	}
+}
+
+func TestPool_Utilization(t *testing.T) {
+	p := New([]int{1, 2}, 0)
+	worker, _ := p.Acquire(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	p.Release(worker)
+
+	// One of two workers was busy for most of the pool's life
+	if u := p.Stats().Utilization; u <= 0.25 || u > 0.5 {
+		t.Errorf("Expected utilization between 0.25 and 0.5, but got %f", u)
+	}
+}
+
+// waitForQueue waits until depth callers are queued.
+func waitForQueue(t *testing.T, p *Pool[int], depth int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for p.Stats().QueueDepth < depth {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d queued callers", depth)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPool_Priority(t *testing.T) {
+	p := New([]int{1}, 4)
+	worker, _ := p.Acquire(context.Background())
+
+	order := make(chan string, 3)
+	acquire := func(name string, priority Priority) {
+		w, err := p.Acquire(WithPriority(context.Background(), priority))
+		if err != nil {
+			t.Errorf("Acquire failed: %v", err)
+			return
+		}
+		order <- name
+		p.Release(w)
+	}
+	go acquire("batch", PriorityLow)
+	waitForQueue(t, p, 1)
+	go acquire("interactive 1", PriorityHigh)
+	waitForQueue(t, p, 2)
+	go acquire("interactive 2", PriorityHigh)
+	waitForQueue(t, p, 3)
+
+	if stats := p.Stats(); stats.QueueDepthHigh != 2 || stats.QueueDepthLow != 1 {
+		t.Errorf("Expected 2 high and 1 low priority waiters, but got %+v", stats)
+	}
+
+	p.Release(worker)
+	got := []string{<-order, <-order, <-order}
+	if got[0] != "interactive 1" || got[1] != "interactive 2" || got[2] != "batch" {
+		t.Errorf("Expected interactive callers first, in order, but got %v", got)
+	}
+}
+
+func TestPool_EstimatedWait(t *testing.T) {
+	p := New([]int{1}, 4)
+	if wait := p.EstimatedWait(PriorityHigh); wait != 0 {
+		t.Errorf("Expected no wait with an idle worker, but got %v", wait)
+	}
+
+	for range 4 {
+		worker, _ := p.Acquire(context.Background())
+		p.Release(worker)
+	}
+	worker, _ := p.Acquire(context.Background())
+	defer p.Release(worker)
+	go p.Acquire(WithPriority(context.Background(), PriorityLow))
+	waitForQueue(t, p, 1)
+
+	// 4 releases within the first second give a throughput of 4 per second
+	if rate := p.Stats().Throughput; rate != 4 {
+		t.Errorf("Expected a throughput of 4 per second, but got %f", rate)
+	}
+	high, low := p.EstimatedWait(PriorityHigh), p.EstimatedWait(PriorityLow)
+	if high != 250*time.Millisecond || low != 500*time.Millisecond {
+		t.Errorf("Expected waits of 250ms ahead of and 500ms behind the queued caller, but got %v and %v", high, low)
+	}
+}
@@ -0,0 +1,295 @@
+// Package social prepares social media posts for entity extraction. Hashtags
+// are segmented into words, mentions are resolved through a handle dictionary
+// and emoji are set aside, keeping offsets into the original post.
+package social
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ner-service-go/internal/offsetmap"
+)
+
+// Span kinds reported by Parse.
+const (
+	KindHashtag = "HASHTAG"
+	KindMention = "MENTION"
+	KindEmoji   = "EMOJI"
+)
+
+// Span is the byte range [Start, End) of a hashtag, mention or emoji in the
+// original post.
+type Span struct {
+	Start int
+	End   int
+	Kind  string
+	// Entity is the dictionary entry of a mention, when its handle is known.
+	Entity *HandleEntity
+}
+
+// HandleEntity is the entity an account handle stands for.
+type HandleEntity struct {
+	Tag  string
+	Name string
+}
+
+// Handles maps lowercased account handles, without the '@', to entities.
+type Handles map[string]HandleEntity
+
+// Post is a post rewritten for entity extraction.
+type Post struct {
+	// Text has hashtags split into words ("#PedroSánchez" becomes
+	// "Pedro Sánchez"), known mentions replaced by their entity name and
+	// emoji replaced by spaces.
+	Text string
+	// Offsets maps Text back to the original post.
+	Offsets *offsetmap.Map
+	// Spans are the hashtags, mentions and emoji of the original post.
+	Spans []Span
+}
+
+// ReadHandles parses a handle dictionary in "@handle<TAB>TAG<TAB>name"
+// format, one entry per line. The name defaults to the handle. Blank lines
+// and lines starting with '#' are ignored.
+func ReadHandles(r io.Reader) (Handles, error) {
+	handles := make(Handles)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		handle := strings.TrimPrefix(strings.TrimSpace(fields[0]), "@")
+		if len(fields) < 2 || handle == "" || strings.TrimSpace(fields[1]) == "" {
+			return nil, fmt.Errorf("handles line %d: expected @handle<TAB>TAG<TAB>name", line)
+		}
+		entity := HandleEntity{Tag: strings.TrimSpace(fields[1]), Name: handle}
+		if len(fields) > 2 && strings.TrimSpace(fields[2]) != "" {
+			entity.Name = strings.TrimSpace(fields[2])
+		}
+		handles[strings.ToLower(handle)] = entity
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read handles: %w", err)
+	}
+	return handles, nil
+}
+
+// LoadHandles reads a handle dictionary from path.
+func LoadHandles(path string) (Handles, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open handles file: %w", err)
+	}
+	defer f.Close()
+
+	return ReadHandles(f)
+}
+
+// Parse finds the hashtags, mentions and emoji of text and rewrites it for
+// entity extraction. handles may be nil.
+func Parse(text string, handles Handles) *Post {
+	post := &Post{}
+	b := offsetmap.NewBuilder(len(text))
+	pos := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		if (r == '#' || r == '@') && startsTag(text, i) {
+			if end := matchTag(text, i+size, r == '@'); end > i+size {
+				b.Copy(text[pos:i], pos)
+				span := Span{Start: i, End: end, Kind: KindHashtag}
+				if r == '@' {
+					span.Kind = KindMention
+					if entity, ok := handles[strings.ToLower(text[i+size:end])]; ok {
+						span.Entity = &entity
+					}
+				}
+				if span.Entity != nil {
+					b.Write(span.Entity.Name, i, end)
+				} else {
+					writeSegments(b, text, i+size, end)
+				}
+				post.Spans = append(post.Spans, span)
+				i, pos = end, end
+				continue
+			}
+		}
+
+		if isEmoji(r) {
+			end := matchEmoji(text, i)
+			b.Copy(text[pos:i], pos)
+			b.Write(" ", i, end)
+			post.Spans = append(post.Spans, Span{Start: i, End: end, Kind: KindEmoji})
+			i, pos = end, end
+			continue
+		}
+
+		i += size
+	}
+	b.Copy(text[pos:], pos)
+
+	post.Text, post.Offsets = b.Finish(len(text))
+	return post
+}
+
+// startsTag reports whether a '#' or '@' at i can start a hashtag or mention,
+// as opposed to being part of a URL or e-mail address.
+func startsTag(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRuneInString(text[:i])
+	return unicode.IsSpace(prev) || strings.ContainsRune("([{¡¿\"'«“‘,;:", prev)
+}
+
+// matchTag returns the end of the hashtag or mention body starting at i, or
+// i when there is none. Hashtags need at least one letter.
+func matchTag(text string, i int, mention bool) int {
+	end, letters := i, 0
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '_':
+		case r == '.' && mention:
+		default:
+			return trimTag(text, i, end, letters)
+		}
+		end += size
+	}
+	return trimTag(text, i, end, letters)
+}
+
+// trimTag drops trailing periods, which end the sentence rather than the
+// handle.
+func trimTag(text string, start, end, letters int) int {
+	for end > start && text[end-1] == '.' {
+		end--
+	}
+	if letters == 0 {
+		return start
+	}
+	return end
+}
+
+// writeSegments writes the words of a hashtag or handle body separated by
+// spaces, each mapped to its range in text.
+func writeSegments(b *offsetmap.Builder, text string, start, end int) {
+	for i, seg := range Segment(text[start:end]) {
+		if i > 0 {
+			b.Write(" ", start+seg[0], start+seg[0])
+		}
+		b.Copy(text[start+seg[0]:start+seg[1]], start+seg[0])
+	}
+}
+
+// Segment splits a hashtag or handle body into words at underscores, periods,
+// CamelCase humps ("PedroSánchez"), the end of capitalized acronyms
+// ("ONUMujeres") and letter-digit changes ("Madrid2024"). It returns the
+// byte range of each word.
+func Segment(tag string) [][2]int {
+	var segments [][2]int
+	start := -1
+	var prev rune
+	for i := 0; i < len(tag); {
+		r, size := utf8.DecodeRuneInString(tag[i:])
+		if r == '_' || r == '.' {
+			if start >= 0 {
+				segments = append(segments, [2]int{start, i})
+				start = -1
+			}
+			i += size
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) {
+			i += size
+			continue
+		}
+
+		next, _ := utf8.DecodeRuneInString(tag[i+size:])
+		if start < 0 {
+			start = i
+		} else if isBoundary(prev, r, next) {
+			segments = append(segments, [2]int{start, i})
+			start = i
+		}
+		prev = r
+		i += size
+	}
+	if start >= 0 {
+		segments = append(segments, [2]int{start, len(tag)})
+	}
+	return segments
+}
+
+func isBoundary(prev, r, next rune) bool {
+	switch {
+	case unicode.IsLower(prev) && unicode.IsUpper(r):
+		return true
+	case unicode.IsUpper(prev) && unicode.IsUpper(r) && unicode.IsLower(next):
+		return true
+	case unicode.IsLetter(prev) && unicode.IsDigit(r), unicode.IsDigit(prev) && unicode.IsLetter(r):
+		return true
+	}
+	return false
+}
+
+// isEmoji reports whether r starts an emoji: pictographs, symbols, dingbats
+// and regional indicator (flag) letters.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return true
+	case r >= 0x2600 && r <= 0x27BF:
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	case r == 0x2122 || r == 0x2139 || r == 0x231A || r == 0x231B || r == 0x23F0 || r == 0x23F3:
+		return true
+	}
+	return false
+}
+
+// isEmojiModifier reports whether r continues an emoji sequence: variation
+// selectors, skin tones, the keycap mark and tag characters.
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F || r == 0xFE0E || r == 0x20E3 ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) || (r >= 0xE0020 && r <= 0xE007F)
+}
+
+// matchEmoji returns the end of the emoji sequence starting at i, joining
+// modifiers, zero-width-joined pictographs and flag pairs into one span.
+func matchEmoji(text string, i int) int {
+	first, size := utf8.DecodeRuneInString(text[i:])
+	end := i + size
+	flag := first >= 0x1F1E6 && first <= 0x1F1FF
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		switch {
+		case isEmojiModifier(r):
+			end += size
+		case r == 0x200D:
+			next, nextSize := utf8.DecodeRuneInString(text[end+size:])
+			if !isEmoji(next) {
+				return end
+			}
+			end += size + nextSize
+		case flag && r >= 0x1F1E6 && r <= 0x1F1FF:
+			end += size
+			flag = false
+		default:
+			return end
+		}
+	}
+	return end
+}
@@ -0,0 +1,129 @@
+package social
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSegment(t *testing.T) {
+	tests := map[string][]string{
+		"PedroSánchez":   {"Pedro", "Sánchez"},
+		"ONUMujeres":     {"ONU", "Mujeres"},
+		"Madrid2024":     {"Madrid", "2024"},
+		"pedro_sanchez":  {"pedro", "sanchez"},
+		"real.madrid":    {"real", "madrid"},
+		"madrid":         {"madrid"},
+		"ElRealMadridCF": {"El", "Real", "Madrid", "CF"},
+	}
+
+	for tag, expected := range tests {
+		var words []string
+		for _, seg := range Segment(tag) {
+			words = append(words, tag[seg[0]:seg[1]])
+		}
+		if strings.Join(words, " ") != strings.Join(expected, " ") {
+			t.Errorf("Expected %v for %q, but got %v", expected, tag, words)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	handles := Handles{"telefonica": {Tag: "ORGANIZATION", Name: "Telefónica"}}
+	text := "Hoy con #PedroSánchez y @Telefonica \U0001F44D\U0001F3FD en Madrid @desconocido_es"
+
+	post := Parse(text, handles)
+
+	expectedText := "Hoy con Pedro Sánchez y Telefónica   en Madrid desconocido es"
+	if post.Text != expectedText {
+		t.Errorf("Expected text %q, but got %q", expectedText, post.Text)
+	}
+
+	expected := []struct {
+		kind  string
+		label string
+	}{
+		{KindHashtag, "#PedroSánchez"},
+		{KindMention, "@Telefonica"},
+		{KindEmoji, "\U0001F44D\U0001F3FD"},
+		{KindMention, "@desconocido_es"},
+	}
+	if len(post.Spans) != len(expected) {
+		t.Fatalf("Expected %d spans, but got %d: %+v", len(expected), len(post.Spans), post.Spans)
+	}
+	for i, span := range post.Spans {
+		if span.Kind != expected[i].kind || text[span.Start:span.End] != expected[i].label {
+			t.Errorf("Expected %s %q, but got %s %q", expected[i].kind, expected[i].label, span.Kind, text[span.Start:span.End])
+		}
+	}
+	if post.Spans[1].Entity == nil || post.Spans[1].Entity.Name != "Telefónica" {
+		t.Errorf("Expected @Telefonica to resolve through the dictionary, but got %+v", post.Spans[1].Entity)
+	}
+	if post.Spans[3].Entity != nil {
+		t.Errorf("Expected unknown handle to stay unresolved, but got %+v", post.Spans[3].Entity)
+	}
+
+	// "Pedro Sánchez" in the rewritten text maps back into the hashtag
+	start := strings.Index(post.Text, "Pedro Sánchez")
+	s, e := post.Offsets.Span(start, start+len("Pedro Sánchez"))
+	if text[s:e] != "PedroSánchez" {
+		t.Errorf("Expected offsets to map to %q, but got %q", "PedroSánchez", text[s:e])
+	}
+}
+
+func TestParse_NotTags(t *testing.T) {
+	tests := []string{
+		"Escribe a ana@example.com",
+		"Visita https://example.com/page#seccion",
+		"Es el número #1",
+		"Un @ suelto",
+	}
+
+	for _, text := range tests {
+		post := Parse(text, nil)
+		if len(post.Spans) != 0 {
+			t.Errorf("Expected no spans in %q, but got %+v", text, post.Spans)
+		}
+		if post.Text != text {
+			t.Errorf("Expected %q unchanged, but got %q", text, post.Text)
+		}
+	}
+}
+
+func TestParse_EmojiSequences(t *testing.T) {
+	tests := map[string]string{
+		"flag":               "\U0001F1EA\U0001F1F8",
+		"zwj":                "\U0001F468\u200d\U0001F469\u200d\U0001F467",
+		"variation selector": "\u2764\ufe0f",
+		"modifier":           "\U0001F44B\U0001F3FB",
+	}
+
+	for name, emoji := range tests {
+		post := Parse("Hola "+emoji+" Madrid", nil)
+		if len(post.Spans) != 1 || post.Spans[0].Kind != KindEmoji || post.Spans[0].End-post.Spans[0].Start != len(emoji) {
+			t.Errorf("%s: expected one emoji span of %d bytes, but got %+v", name, len(emoji), post.Spans)
+		}
+	}
+}
+
+func TestReadHandles(t *testing.T) {
+	input := "# handle dictionary\n@Telefonica\tORGANIZATION\tTelefónica\nsanchezcastejon\tPERSON\tPedro Sánchez\n@rae\tORGANIZATION\n"
+	handles, err := ReadHandles(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadHandles failed: %v", err)
+	}
+
+	expected := Handles{
+		"telefonica":      {Tag: "ORGANIZATION", Name: "Telefónica"},
+		"sanchezcastejon": {Tag: "PERSON", Name: "Pedro Sánchez"},
+		"rae":             {Tag: "ORGANIZATION", Name: "rae"},
+	}
+	for handle, entity := range expected {
+		if handles[handle] != entity {
+			t.Errorf("Expected %+v for %q, but got %+v", entity, handle, handles[handle])
+		}
+	}
+
+	if _, err := ReadHandles(strings.NewReader("@solo\n")); err == nil {
+		t.Error("Expected an error for a line without a tag")
+	}
+}
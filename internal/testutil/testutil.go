@@ -1,5 +1,7 @@
 package testutil
 
+import "ner-service-go/internal/canary"
+
 // SpanishTestTexts contains common Spanish test texts for NER testing
 var SpanishTestTexts = struct {
 	PersonLocation string
@@ -9,7 +11,7 @@ var SpanishTestTexts = struct {
 	NoEntities     string
 	Empty          string
 }{
-	PersonLocation: "María García vive en Madrid",
+	PersonLocation: canary.Text,
 	Organization:   "Trabajo en Microsoft España",
 	Mixed:          "Pedro Sánchez visitó Barcelona para reunirse con representantes de Telefónica",
 	Complex:        "El presidente del Real Madrid, Florentino Pérez, se reunió con Karim Benzema en el Santiago Bernabéu",
@@ -24,3 +26,72 @@ var ExpectedEntityTypes = []string{
 	"ORGANIZATION",
 	"MISC",
 }
+
+// SpanishTrainingData is a small CoNLL-formatted corpus covering
+// SpanishTestTexts, used to train perceptron models in tests without
+// downloading a real model.
+const SpanishTrainingData = `María B-PER
+García I-PER
+vive O
+en O
+Madrid B-LOC
+
+Trabajo O
+en O
+Microsoft B-ORG
+España I-ORG
+
+Pedro B-PER
+Sánchez I-PER
+visitó O
+Barcelona B-LOC
+para O
+reunirse O
+con O
+representantes O
+de O
+Telefónica B-ORG
+
+El O
+presidente O
+del O
+Real B-ORG
+Madrid I-ORG
+, O
+Florentino B-PER
+Pérez I-PER
+, O
+se O
+reunió O
+con O
+Karim B-PER
+Benzema I-PER
+en O
+el O
+Santiago B-LOC
+Bernabéu I-LOC
+
+El O
+día O
+está O
+muy O
+soleado O
+y O
+hace O
+calor O
+
+Ana B-PER
+Martínez I-PER
+vive O
+en O
+Sevilla B-LOC
+y O
+trabaja O
+en O
+Iberdrola B-ORG
+
+Luis B-PER
+Gómez I-PER
+visitó O
+Valencia B-LOC
+`
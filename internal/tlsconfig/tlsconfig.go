@@ -0,0 +1,219 @@
+// Package tlsconfig builds the TLS configuration of the HTTP server from
+// certificate, key and client CA files, and reloads them when they change.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ClientAuthRequire rejects clients without a certificate signed by the
+	// client CA.
+	ClientAuthRequire = "require"
+	// ClientAuthOptional verifies client certificates when clients send one.
+	ClientAuthOptional = "optional"
+)
+
+// Options configure the server's TLS.
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// MinVersion is "1.0", "1.1", "1.2" or "1.3". Empty means "1.2".
+	MinVersion string
+	// CipherSuites are the names of the TLS 1.2 cipher suites allowed, such
+	// as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Empty means Go's secure
+	// defaults. TLS 1.3 suites are not configurable.
+	CipherSuites []string
+	// ClientCAFile, when set, is the PEM bundle client certificates are
+	// verified against, as ClientAuth requires.
+	ClientCAFile string
+	ClientAuth   string
+}
+
+// Reloader serves the certificate and client CAs loaded from the files of
+// its options, picking up new files when Reload is called.
+type Reloader struct {
+	opts         Options
+	minVersion   uint16
+	cipherSuites []uint16
+	clientAuth   tls.ClientAuthType
+
+	mu      sync.RWMutex
+	config  *tls.Config
+	modTime map[string]time.Time
+}
+
+// New loads the certificate, key and client CAs of opts.
+func New(opts Options) (*Reloader, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("TLS requires both a certificate and a key file")
+	}
+	minVersion, err := parseVersion(opts.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := parseCipherSuites(opts.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reloader{
+		opts:         opts,
+		minVersion:   minVersion,
+		cipherSuites: cipherSuites,
+		clientAuth:   tls.NoClientCert,
+	}
+	if opts.ClientCAFile != "" {
+		switch opts.ClientAuth {
+		case "", ClientAuthRequire:
+			r.clientAuth = tls.RequireAndVerifyClientCert
+		case ClientAuthOptional:
+			r.clientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, fmt.Errorf("unknown client auth mode %q, expected %s or %s", opts.ClientAuth, ClientAuthRequire, ClientAuthOptional)
+		}
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the configuration for an http.Server. Each handshake
+// uses the files loaded last.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.minVersion,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.current().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
+
+// Reload loads the files again if any of them changed since they were
+// loaded. When they cannot be loaded, for example while they are being
+// replaced, the previous certificate and CAs stay in use.
+func (r *Reloader) Reload() (bool, error) {
+	changed := false
+	for _, path := range r.files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, fmt.Errorf("failed to check %s: %w", path, err)
+		}
+		r.mu.RLock()
+		changed = changed || !info.ModTime().Equal(r.modTime[path])
+		r.mu.RUnlock()
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, r.load()
+}
+
+func (r *Reloader) current() *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientCAFile != "" {
+		files = append(files, r.opts.ClientCAFile)
+	}
+	return files
+}
+
+func (r *Reloader) load() error {
+	// Take the modification times first, so that files replaced while they
+	// are read are loaded again by the next Reload
+	modTime := make(map[string]time.Time)
+	for _, path := range r.files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", path, err)
+		}
+		modTime[path] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   r.minVersion,
+		CipherSuites: r.cipherSuites,
+		ClientAuth:   r.clientAuth,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA bundle %s", r.opts.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.config, r.modTime = config, modTime
+	r.mu.Unlock()
+	return nil
+}
+
+func parseVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown TLS version %q, expected 1.0, 1.1, 1.2 or 1.3", version)
+	}
+}
+
+// parseCipherSuites returns the IDs of the named cipher suites. Only suites
+// Go considers secure are accepted.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ClientSubject returns the subject of the verified client certificate of
+// a connection, or "" when the client sent none.
+func ClientSubject(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return state.VerifiedChains[0][0].Subject.String()
+}
@@ -0,0 +1,210 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate with its key, signed by parent or self-signed.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, cn string, isCA bool, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn, Organization: []string{"Newsroom"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write saves the certificate and key as PEM files and returns their paths.
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	keyDER, _ := x509.MarshalECPrivateKey(c.key)
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certPath, keyPath
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// serve starts an HTTPS server answering with the client certificate
+// subject, and returns its address.
+func serve(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, ClientSubject(r.TLS))
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return listener.Addr().String()
+}
+
+// get requests addr with the given client certificates, trusting ca, and
+// returns the body and the server certificate's common name.
+func get(addr string, ca *testCert, certs ...tls.Certificate) (string, string, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+		DisableKeepAlives: true,
+	}}
+	resp, err := client.Get("https://" + addr)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body), resp.TLS.PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "Test CA", true, nil)
+	caPath, _ := ca.write(t, dir, "ca")
+	certPath, keyPath := newTestCert(t, "server", false, ca).write(t, dir, "server")
+	client := newTestCert(t, "reporter", false, ca)
+	stranger := newTestCert(t, "stranger", false, newTestCert(t, "Other CA", true, nil))
+
+	r, err := New(Options{CertFile: certPath, KeyFile: keyPath, ClientCAFile: caPath})
+	if err != nil {
+		t.Fatalf("Failed to load TLS configuration: %v", err)
+	}
+	addr := serve(t, r.TLSConfig())
+
+	subject, _, err := get(addr, ca, client.tlsCertificate())
+	if err != nil {
+		t.Fatalf("Failed to connect with a client certificate: %v", err)
+	}
+	if subject != "CN=reporter,O=Newsroom" {
+		t.Errorf("Expected the client subject CN=reporter,O=Newsroom, but got %q", subject)
+	}
+	if _, _, err := get(addr, ca); err == nil {
+		t.Errorf("Expected clients without a certificate to be rejected")
+	}
+	if _, _, err := get(addr, ca, stranger.tlsCertificate()); err == nil {
+		t.Errorf("Expected clients with a certificate from another CA to be rejected")
+	}
+}
+
+func TestReloader_OptionalClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "Test CA", true, nil)
+	caPath, _ := ca.write(t, dir, "ca")
+	certPath, keyPath := newTestCert(t, "server", false, ca).write(t, dir, "server")
+
+	r, err := New(Options{CertFile: certPath, KeyFile: keyPath, ClientCAFile: caPath, ClientAuth: ClientAuthOptional})
+	if err != nil {
+		t.Fatalf("Failed to load TLS configuration: %v", err)
+	}
+	subject, _, err := get(serve(t, r.TLSConfig()), ca)
+	if err != nil || subject != "" {
+		t.Errorf("Expected clients without a certificate to be served without a subject, but got %q, %v", subject, err)
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "Test CA", true, nil)
+	certPath, keyPath := newTestCert(t, "first", false, ca).write(t, dir, "server")
+
+	r, err := New(Options{CertFile: certPath, KeyFile: keyPath, MinVersion: "1.3"})
+	if err != nil {
+		t.Fatalf("Failed to load TLS configuration: %v", err)
+	}
+	addr := serve(t, r.TLSConfig())
+	if changed, err := r.Reload(); changed || err != nil {
+		t.Errorf("Expected no reload for unchanged files, but got %v, %v", changed, err)
+	}
+
+	newTestCert(t, "second", false, ca).write(t, dir, "server")
+	// Make sure the modification times change on coarse filesystems
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certPath, later, later)
+	os.Chtimes(keyPath, later, later)
+	if changed, err := r.Reload(); !changed || err != nil {
+		t.Fatalf("Expected the new certificate to be loaded, but got %v, %v", changed, err)
+	}
+	if _, cn, err := get(addr, ca); err != nil || cn != "second" {
+		t.Errorf("Expected the server to present the second certificate, but got %q, %v", cn, err)
+	}
+
+	// A broken file keeps the last good certificate in use
+	os.WriteFile(keyPath, []byte("not a key"), 0o600)
+	later = later.Add(time.Minute)
+	os.Chtimes(keyPath, later, later)
+	if _, err := r.Reload(); err == nil {
+		t.Errorf("Expected an error for a broken key file")
+	}
+	if _, cn, err := get(addr, ca); err != nil || cn != "second" {
+		t.Errorf("Expected the second certificate to stay in use, but got %q, %v", cn, err)
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := newTestCert(t, "server", false, nil).write(t, dir, "server")
+
+	for name, opts := range map[string]Options{
+		"missing key":      {CertFile: certPath},
+		"unknown version":  {CertFile: certPath, KeyFile: keyPath, MinVersion: "2.0"},
+		"insecure cipher":  {CertFile: certPath, KeyFile: keyPath, CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+		"unknown auth":     {CertFile: certPath, KeyFile: keyPath, ClientCAFile: certPath, ClientAuth: "sometimes"},
+		"empty CA bundle":  {CertFile: certPath, KeyFile: keyPath, ClientCAFile: keyPath},
+		"missing CA files": {CertFile: certPath, KeyFile: keyPath, ClientCAFile: filepath.Join(dir, "missing.pem")},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+
+	if _, err := New(Options{CertFile: certPath, KeyFile: keyPath, CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}}); err != nil {
+		t.Errorf("Expected a secure cipher suite to be accepted, but got %v", err)
+	}
+}
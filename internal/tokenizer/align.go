@@ -0,0 +1,30 @@
+package tokenizer
+
+import "strings"
+
+// Align recovers byte offsets for tokens produced by another tokenizer, such
+// as MITIE's, by locating each token in text in order. A token that cannot be
+// found gets an empty range at the current position.
+func Align(text string, words []string) []Token {
+	tokens := make([]Token, len(words))
+	pos := 0
+	for i, word := range words {
+		if idx := strings.Index(text[pos:], word); idx >= 0 {
+			start := pos + idx
+			tokens[i] = Token{Text: word, Start: start, End: start + len(word)}
+			pos = start + len(word)
+			continue
+		}
+		tokens[i] = Token{Text: word, Start: pos, End: pos}
+	}
+	return tokens
+}
+
+// Texts returns the text of each token.
+func Texts(tokens []Token) []string {
+	texts := make([]string, len(tokens))
+	for i, t := range tokens {
+		texts[i] = t.Text
+	}
+	return texts
+}
@@ -0,0 +1,388 @@
+// Package tokenizer splits Spanish text into tokens that keep the exact byte
+// offsets of their source text.
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a token with the byte range [Start, End) it covers in the input.
+// Text is normally input[Start:End]; it only differs for split contractions,
+// where "del" yields "de" and "el".
+type Token struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Options selects the Spanish rules applied by a Tokenizer.
+type Options struct {
+	// Hashtags keeps "#PedroSánchez" as a single token.
+	Hashtags bool
+	// Mentions keeps "@Telefonica" as a single token.
+	Mentions bool
+	// URLs keeps URLs and e-mail addresses as single tokens.
+	URLs bool
+	// SplitContractions splits "del" and "al" into "de el" and "a el".
+	SplitContractions bool
+	// SplitClitics splits enclitic pronouns from infinitives, gerunds and
+	// accented imperatives, e.g. "decírselo" into "decír", "se" and "lo".
+	SplitClitics bool
+	// Abbreviations are extra abbreviations, without the trailing period,
+	// added to the built-in Spanish list.
+	Abbreviations []string
+}
+
+// DefaultOptions returns the options used by the service.
+func DefaultOptions() Options {
+	return Options{
+		Hashtags: true,
+		Mentions: true,
+		URLs:     true,
+	}
+}
+
+// Tokenizer splits text using Spanish tokenization rules.
+type Tokenizer struct {
+	opts          Options
+	abbreviations map[string]bool
+}
+
+// New returns a Tokenizer for the given options.
+func New(opts Options) *Tokenizer {
+	abbreviations := make(map[string]bool, len(spanishAbbreviations)+len(opts.Abbreviations))
+	for _, a := range spanishAbbreviations {
+		abbreviations[a] = true
+	}
+	for _, a := range opts.Abbreviations {
+		abbreviations[strings.ToLower(strings.TrimSuffix(a, "."))] = true
+	}
+	return &Tokenizer{opts: opts, abbreviations: abbreviations}
+}
+
+// spanishAbbreviations are common abbreviations written with a trailing
+// period, lowercased and without it.
+var spanishAbbreviations = []string{
+	"sr", "sra", "srta", "sres", "dr", "dra", "dña", "lic", "ing", "arq",
+	"prof", "profa", "excmo", "excma", "ilmo", "ilma", "sto", "sta", "etc",
+	"pág", "págs", "núm", "nº", "art", "vol", "cap", "tel", "avda", "av",
+	"pza", "cía", "admón", "aprox", "dpto", "depto", "ej", "vs", "gral",
+	"cnel", "tte", "sgto", "mons", "fdo",
+}
+
+// Tokenize splits text into tokens.
+func (t *Tokenizer) Tokenize(text string) []Token {
+	tokens := make([]Token, 0, len(text)/5+1)
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) || isInvisible(r) {
+			i += size
+			continue
+		}
+
+		end := t.match(text, i)
+		tokens = t.appendToken(tokens, text, i, end)
+		i = end
+	}
+
+	return tokens
+}
+
+// match returns the end of the token starting at i.
+func (t *Tokenizer) match(text string, i int) int {
+	r, size := utf8.DecodeRuneInString(text[i:])
+
+	if t.opts.URLs {
+		if end := matchURL(text, i); end > i {
+			return end
+		}
+	}
+	if (r == '#' && t.opts.Hashtags) || (r == '@' && t.opts.Mentions) {
+		if end := scanWord(text, i+size); end > i+size {
+			return end
+		}
+	}
+	if unicode.IsDigit(r) {
+		end := scanNumber(text, i)
+		if next, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && unicode.IsLetter(next) {
+			// Alphanumeric words such as "4G" or "3D"
+			return scanWord(text, i)
+		}
+		return end
+	}
+	if isWordRune(r) {
+		return t.scanWordWithAbbreviation(text, i)
+	}
+	if r == '.' && strings.HasPrefix(text[i:], "...") {
+		return i + 3
+	}
+
+	// Inverted punctuation, guillemets, typographic quotes, dashes and all
+	// other symbols are tokens on their own.
+	return i + size
+}
+
+func (t *Tokenizer) appendToken(tokens []Token, text string, start, end int) []Token {
+	word := text[start:end]
+
+	if t.opts.SplitContractions {
+		switch strings.ToLower(word) {
+		case "del":
+			return append(tokens,
+				Token{Text: word[:2], Start: start, End: start + 2},
+				Token{Text: "el", Start: start + 2, End: end})
+		case "al":
+			return append(tokens,
+				Token{Text: word[:1], Start: start, End: start + 1},
+				Token{Text: "el", Start: start + 1, End: end})
+		}
+	}
+
+	if t.opts.SplitClitics {
+		if cut := splitClitics(word); len(cut) > 1 {
+			pos := start
+			for _, part := range cut {
+				tokens = append(tokens, Token{Text: part, Start: pos, End: pos + len(part)})
+				pos += len(part)
+			}
+			return tokens
+		}
+	}
+
+	return append(tokens, Token{Text: word, Start: start, End: end})
+}
+
+// scanWordWithAbbreviation scans a word and keeps a trailing period when the
+// word is a known abbreviation, a single-letter initial ("J.") or a dotted
+// acronym ("EE.UU.", "S.A.").
+func (t *Tokenizer) scanWordWithAbbreviation(text string, i int) int {
+	end := scanWord(text, i)
+	if end >= len(text) || text[end] != '.' {
+		return end
+	}
+
+	// Dotted acronyms: letter groups joined by periods
+	acronymEnd := end
+	for acronymEnd < len(text) && text[acronymEnd] == '.' {
+		next := scanLetters(text, acronymEnd+1)
+		if next == acronymEnd+1 {
+			break
+		}
+		acronymEnd = next
+	}
+	if acronymEnd > end {
+		if acronymEnd < len(text) && text[acronymEnd] == '.' {
+			return acronymEnd + 1
+		}
+		return acronymEnd
+	}
+
+	word := text[i:end]
+	if utf8.RuneCountInString(word) == 1 && unicode.IsUpper([]rune(word)[0]) {
+		return end + 1
+	}
+	if t.abbreviations[strings.ToLower(word)] {
+		return end + 1
+	}
+	return end
+}
+
+// scanWord scans letters and digits, keeping inner hyphens and apostrophes
+// between word characters ("Álvarez-Pallete", "O'Donnell").
+func scanWord(text string, i int) int {
+	end := i
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if isWordRune(r) || unicode.IsDigit(r) {
+			end += size
+			continue
+		}
+		if (r == '-' || r == '\'' || r == '’') && end > i {
+			next, _ := utf8.DecodeRuneInString(text[end+size:])
+			if isWordRune(next) {
+				end += size
+				continue
+			}
+		}
+		break
+	}
+	return end
+}
+
+func scanLetters(text string, i int) int {
+	end := i
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(r) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+// scanNumber scans digits with decimal commas and thousands separators, so
+// "3,5", "1.000.000" and "2.500,75" are single tokens. A separator is only
+// kept when a digit follows it.
+func scanNumber(text string, i int) int {
+	end := i
+	for end < len(text) {
+		c := text[end]
+		if c >= '0' && c <= '9' {
+			end++
+			continue
+		}
+		if (c == ',' || c == '.') && end+1 < len(text) && text[end+1] >= '0' && text[end+1] <= '9' {
+			end++
+			continue
+		}
+		break
+	}
+	// Ordinals and units glued to the number, e.g. "1º" or "2ª"
+	if end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if r == 'º' || r == 'ª' {
+			end += size
+		}
+	}
+	return end
+}
+
+// matchURL returns the end of a URL or e-mail address starting at i, or i.
+func matchURL(text string, i int) int {
+	rest := text[i:]
+	lower := strings.ToLower(rest[:min(len(rest), 8)])
+	isURL := strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "www.")
+
+	end := i
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if unicode.IsSpace(r) || strings.ContainsRune("\"<>«»“”", r) {
+			break
+		}
+		end += size
+	}
+	candidate := text[i:end]
+	if !isURL {
+		at := strings.IndexByte(candidate, '@')
+		if at <= 0 || !strings.Contains(candidate[at:], ".") {
+			return i
+		}
+	}
+
+	// Trailing punctuation belongs to the sentence, not the URL
+	for end > i {
+		r, size := utf8.DecodeLastRuneInString(text[i:end])
+		if !strings.ContainsRune(".,;:!?)]}'’", r) {
+			break
+		}
+		end -= size
+	}
+	return end
+}
+
+// enclitics are the pronouns that can be attached to a verb, longest first.
+// "os" is left out: plurals such as "primeros" and "claros" far outnumber
+// forms like "iros".
+var enclitics = []string{"nos", "les", "los", "las", "me", "te", "se", "le", "lo", "la"}
+
+// cliticExceptions are words that look like a verb with enclitic pronouns
+// but are not. Plurals are matched through their singular.
+var cliticExceptions = map[string]bool{
+	"aparte": true, "baluarte": true, "estandarte": true, "descarte": true,
+	"comparte": true, "reparte": true, "imparte": true, "contraparte": true,
+	"fuerte": true, "muerte": true, "suerte": true, "inerte": true,
+	"firme": true, "afirme": true, "confirme": true, "inerme": true,
+	"gendarme": true, "desarme": true,
+	"charla": true, "parla": true, "perla": true,
+	"moderno": true, "posmoderno": true, "interno": true, "externo": true,
+	"eterno": true, "alterno": true, "subalterno": true, "paterno": true,
+	"materno": true, "fraterno": true, "cuaderno": true,
+}
+
+// splitClitics splits enclitic pronouns off infinitives ("decirlo"), gerunds
+// ("diciéndolo") and accented imperatives ("cómpralo"). Capitalized words are
+// left alone since they are likely names. It returns the word unchanged when
+// no split applies.
+func splitClitics(word string) []string {
+	if first, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(first) {
+		return []string{word}
+	}
+	if lower := strings.ToLower(word); cliticExceptions[lower] || cliticExceptions[strings.TrimSuffix(lower, "s")] {
+		return []string{word}
+	}
+	// Suffixes are matched and cut on word itself, since case mapping can
+	// change the byte length of a rune
+	var parts []string
+	stem := word
+	for len(parts) < 2 {
+		found := false
+		for _, c := range enclitics {
+			tail := lastRunes(stem, utf8.RuneCountInString(c))
+			if strings.EqualFold(tail, c) && len(stem) > len(tail)+2 {
+				stem = stem[:len(stem)-len(tail)]
+				parts = append([]string{tail}, parts...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	if len(parts) == 0 || !isVerbStem(strings.ToLower(stem), len(parts)) {
+		return []string{word}
+	}
+	return append([]string{stem}, parts...)
+}
+
+// lastRunes returns the last n runes of s, or s when it is shorter.
+func lastRunes(s string, n int) string {
+	i := len(s)
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+	}
+	return s[i:]
+}
+
+// isVerbStem reports whether stem looks like a verb form that takes enclitic
+// pronouns. Gerunds and imperatives carry a written accent once pronouns are
+// attached, which rules out most nouns ending in "-se" or "-la". No infinitive
+// ends in "-ier" or "-uer", so stems such as "gobier" in "gobiernos" and
+// "duer" in "duerme" are not taken for one.
+func isVerbStem(stem string, pronouns int) bool {
+	switch {
+	case strings.HasSuffix(stem, "ier"), strings.HasSuffix(stem, "uer"):
+		return false
+	case strings.HasSuffix(stem, "ar"), strings.HasSuffix(stem, "er"), strings.HasSuffix(stem, "ir"),
+		strings.HasSuffix(stem, "ár"), strings.HasSuffix(stem, "ér"), strings.HasSuffix(stem, "ír"):
+		return len(stem) > 3
+	case strings.HasSuffix(stem, "ándo"), strings.HasSuffix(stem, "iéndo"), strings.HasSuffix(stem, "yéndo"):
+		return true
+	}
+	// Imperatives end in "a" or "e" ("cómpra-lo", "dígame")
+	last, _ := utf8.DecodeLastRuneInString(stem)
+	return hasAccent(stem) && (last == 'a' || last == 'e' || pronouns > 1)
+}
+
+func hasAccent(s string) bool {
+	return strings.ContainsAny(s, "áéíóú")
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+}
+
+// isInvisible reports zero-width and formatting characters that never belong
+// to a token.
+func isInvisible(r rune) bool {
+	switch r {
+	case '\u200b', '\u200c', '\u200d', '\ufeff', '\u00ad':
+		return true
+	}
+	return false
+}
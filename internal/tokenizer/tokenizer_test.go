@@ -0,0 +1,169 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tokenizer := New(DefaultOptions())
+
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"Simple sentence", "María García vive en Madrid.", []string{"María", "García", "vive", "en", "Madrid", "."}},
+		{"Inverted punctuation", "¿Dónde está Pedro? ¡En Sevilla!", []string{"¿", "Dónde", "está", "Pedro", "?", "¡", "En", "Sevilla", "!"}},
+		{"Guillemets and quotes", "«El País» y “El Mundo”", []string{"«", "El", "País", "»", "y", "“", "El", "Mundo", "”"}},
+		{"Em-dash", "Madrid—capital de España—crece", []string{"Madrid", "—", "capital", "de", "España", "—", "crece"}},
+		{"Decimal comma", "Creció un 3,5 % hasta 2.500,75 euros", []string{"Creció", "un", "3,5", "%", "hasta", "2.500,75", "euros"}},
+		{"Trailing comma after number", "En 2020, Madrid", []string{"En", "2020", ",", "Madrid"}},
+		{"Abbreviations", "El Sr. Pérez y la Dra. López", []string{"El", "Sr.", "Pérez", "y", "la", "Dra.", "López"}},
+		{"Acronyms", "Viajó a EE.UU. con la S.A.", []string{"Viajó", "a", "EE.UU.", "con", "la", "S.A."}},
+		{"Initials", "J. R. R. Tolkien", []string{"J.", "R.", "R.", "Tolkien"}},
+		{"Sentence final period", "Vive en Madrid.", []string{"Vive", "en", "Madrid", "."}},
+		{"Hyphenated surname", "José María Álvarez-Pallete", []string{"José", "María", "Álvarez-Pallete"}},
+		{"Hashtags and mentions", "Gracias @Telefonica #PedroSánchez", []string{"Gracias", "@Telefonica", "#PedroSánchez"}},
+		{"URL", "Visita https://www.rtve.es/noticias.", []string{"Visita", "https://www.rtve.es/noticias", "."}},
+		{"Ellipsis", "Y entonces...", []string{"Y", "entonces", "..."}},
+		{"Alphanumeric", "La red 4G y el COVID-19", []string{"La", "red", "4G", "y", "el", "COVID-19"}},
+		{"Empty", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := tokenizer.Tokenize(tt.text)
+			texts := Texts(tokens)
+			if strings.Join(texts, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected tokens %q, but got %q", tt.expected, texts)
+			}
+		})
+	}
+}
+
+func TestTokenize_Offsets(t *testing.T) {
+	text := "¿Vio a Álvarez-Pallete en «Telefónica»?"
+	tokens := New(DefaultOptions()).Tokenize(text)
+
+	for _, token := range tokens {
+		if text[token.Start:token.End] != token.Text {
+			t.Errorf("Token %q has offsets [%d, %d) covering %q", token.Text, token.Start, token.End, text[token.Start:token.End])
+		}
+	}
+}
+
+func TestTokenize_DisabledRules(t *testing.T) {
+	tokens := Texts(New(Options{}).Tokenize("@Telefonica #Madrid"))
+
+	expected := []string{"@", "Telefonica", "#", "Madrid"}
+	if strings.Join(tokens, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected tokens %q, but got %q", expected, tokens)
+	}
+}
+
+func TestTokenize_Contractions(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SplitContractions = true
+	text := "Vuelve del Real Madrid al Barça"
+	tokens := New(opts).Tokenize(text)
+
+	expected := []string{"Vuelve", "de", "el", "Real", "Madrid", "a", "el", "Barça"}
+	if strings.Join(Texts(tokens), "|") != strings.Join(expected, "|") {
+		t.Fatalf("Expected tokens %q, but got %q", expected, Texts(tokens))
+	}
+
+	// Both halves of the contraction stay within the source word
+	if tokens[1].Start != 7 || tokens[2].End != 10 {
+		t.Errorf("Expected contraction to cover bytes [7, 10), but got [%d, %d)", tokens[1].Start, tokens[2].End)
+	}
+}
+
+func TestTokenize_Clitics(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SplitClitics = true
+	tokenizer := New(opts)
+
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"decirlo", []string{"decir", "lo"}},
+		{"decírselo", []string{"decír", "se", "lo"}},
+		{"diciéndome", []string{"diciéndo", "me"}},
+		{"cómpralo", []string{"cómpra", "lo"}},
+		{"hacernos", []string{"hacer", "nos"}},
+		{"convertirte", []string{"convertir", "te"}},
+		{"muerte", []string{"muerte"}},
+		{"teléfonos", []string{"teléfonos"}},
+		{"Carlos", []string{"Carlos"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			tokens := Texts(tokenizer.Tokenize(tt.text))
+			if strings.Join(tokens, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected tokens %q, but got %q", tt.expected, tokens)
+			}
+		})
+	}
+}
+
+func TestTokenize_CliticsNotVerbs(t *testing.T) {
+	tokenizer := New(Options{SplitClitics: true})
+
+	// Nouns, adjectives and finite verbs that end like an infinitive or
+	// imperative followed by a pronoun
+	words := []string{
+		"gobiernos", "modernos", "internos", "eternos", "tiernos",
+		"convierte", "duerme", "comparte", "confirme",
+		"charlas", "perlas", "primeros", "caballeros", "claros",
+	}
+
+	for _, word := range words {
+		t.Run(word, func(t *testing.T) {
+			tokens := Texts(tokenizer.Tokenize(word))
+			if len(tokens) != 1 || tokens[0] != word {
+				t.Errorf("Expected tokens [%s], but got %q", word, tokens)
+			}
+		})
+	}
+}
+
+// Case mapping changes the byte length of runes such as Ⱥ and İ, so clitics
+// must be cut on the word itself rather than on its lowercase form.
+func TestTokenize_CliticsCaseChangingRunes(t *testing.T) {
+	tokenizer := New(Options{SplitClitics: true})
+
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"aȺȺȺȺȺȺarlo", []string{"aȺȺȺȺȺȺar", "lo"}},
+		{"aⱥⱥⱥⱥⱥⱥarlo", []string{"aⱥⱥⱥⱥⱥⱥar", "lo"}},
+		{"decirLO", []string{"decir", "LO"}},
+		{"decİrlo", []string{"decİr", "lo"}},
+		{"aİİİİİİarlo", []string{"aİİİİİİar", "lo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			tokens := Texts(tokenizer.Tokenize(tt.text))
+			if strings.Join(tokens, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected tokens %q, but got %q", tt.expected, tokens)
+			}
+		})
+	}
+}
+
+func TestAlign(t *testing.T) {
+	text := "Pedro  Sánchez visitó Barcelona"
+	tokens := Align(text, []string{"Pedro", "Sánchez", "visitó", "Barcelona"})
+
+	expected := [][2]int{{0, 5}, {7, 15}, {16, 23}, {24, 33}}
+	for i, token := range tokens {
+		if token.Start != expected[i][0] || token.End != expected[i][1] {
+			t.Errorf("Token %q: expected [%d, %d), but got [%d, %d)", token.Text, expected[i][0], expected[i][1], token.Start, token.End)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Package truecase restores the usual capitalization of text written in all
+// capitals or all lowercase, such as headlines and chat messages, using word
+// casing frequencies learned from a corpus.
+package truecase
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ner-service-go/internal/modelfile"
+	"ner-service-go/internal/offsetmap"
+)
+
+// modelMagic identifies truecasing model files. The trailing byte is the
+// format version.
+var modelMagic = []byte("NERCASE\x01")
+
+// ErrInvalidModel is returned when a file is not a truecasing model.
+var ErrInvalidModel = errors.New("not a truecasing model file")
+
+// minLetters is the number of letters below which casing is not judged.
+const minLetters = 8
+
+// Model maps lowercased words to their most frequent spelling.
+type Model struct {
+	Forms map[string]string
+}
+
+// word is a run of letters and digits in a text.
+type word struct {
+	start, end int
+	// initial is set for the first word of a sentence.
+	initial bool
+}
+
+// Train builds a model from a plain-text corpus. The first word of each
+// sentence is ignored, since it is capitalized regardless of the word, and so
+// are lines that are themselves badly cased.
+func Train(r io.Reader) (*Model, error) {
+	counts := make(map[string]map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if BadlyCased(line) {
+			continue
+		}
+		for _, w := range words(line) {
+			form := line[w.start:w.end]
+			if w.initial || !hasLetter(form) {
+				continue
+			}
+			lower := strings.ToLower(form)
+			if counts[lower] == nil {
+				counts[lower] = make(map[string]int)
+			}
+			counts[lower][form]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+
+	m := &Model{Forms: make(map[string]string, len(counts))}
+	for lower, forms := range counts {
+		best, bestCount := "", 0
+		for form, count := range forms {
+			if count > bestCount || (count == bestCount && form > best) {
+				best, bestCount = form, count
+			}
+		}
+		m.Forms[lower] = best
+	}
+	return m, nil
+}
+
+// BadlyCased reports whether text is written in all capitals or all
+// lowercase. Texts too short to tell are never badly cased.
+func BadlyCased(text string) bool {
+	var letters, upper, lower int
+	for _, r := range text {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		default:
+			continue
+		}
+		letters++
+	}
+	if letters < minLetters {
+		return false
+	}
+	return upper == 0 || lower*20 < letters
+}
+
+// Apply returns text with the usual capitalization of each word when text is
+// badly cased, and text unchanged otherwise. Known words take their most
+// frequent spelling, unknown words in all-capitals text are capitalized as
+// names, and the first word of each sentence is capitalized. The returned map
+// leads back to offsets in text.
+func (m *Model) Apply(text string) (string, *offsetmap.Map) {
+	if !BadlyCased(text) {
+		return text, nil
+	}
+	allCaps := !hasLower(text)
+
+	b := offsetmap.NewBuilder(len(text))
+	pos := 0
+	for _, w := range words(text) {
+		b.Copy(text[pos:w.start], pos)
+		form := m.caseWord(text[w.start:w.end], w.initial, allCaps)
+		if len(form) == w.end-w.start {
+			b.Copy(form, w.start)
+		} else {
+			b.Write(form, w.start, w.end)
+		}
+		pos = w.end
+	}
+	b.Copy(text[pos:], pos)
+	return b.Finish(len(text))
+}
+
+func (m *Model) caseWord(original string, initial, allCaps bool) string {
+	lower := strings.ToLower(original)
+	form, known := m.Forms[lower]
+	switch {
+	case known:
+	case allCaps && hasLetter(original):
+		form = capitalize(lower)
+	default:
+		form = original
+	}
+	if initial {
+		form = capitalize(form)
+	}
+	return form
+}
+
+// words splits text into runs of letters and digits, marking the first word
+// after the start of the text, a line break or sentence punctuation.
+func words(text string) []word {
+	var result []word
+	initial := true
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			result = append(result, word{start: start, end: i, initial: initial})
+			initial = false
+			start = -1
+		}
+		if strings.ContainsRune(".!?¿¡\n", r) {
+			initial = true
+		}
+	}
+	if start >= 0 {
+		result = append(result, word{start: start, end: len(text), initial: initial})
+	}
+	return result
+}
+
+// capitalize uppercases the first letter of s.
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+func hasLetter(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
+
+func hasLower(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLower) >= 0
+}
+
+// modelFile is the serialized form of a Model, with forms sorted so files
+// are reproducible.
+type modelFile struct {
+	Words []string
+	Forms []string
+}
+
+// Save writes the model in the truecasing model format: a magic header
+// followed by a gzip-compressed gob encoding.
+func (m *Model) Save(w io.Writer) error {
+	var file modelFile
+	for lower := range m.Forms {
+		file.Words = append(file.Words, lower)
+	}
+	sort.Strings(file.Words)
+	for _, lower := range file.Words {
+		file.Forms = append(file.Forms, m.Forms[lower])
+	}
+
+	return modelfile.Write(w, modelMagic, &file)
+}
+
+// SaveFile writes the model to path.
+func (m *Model) SaveFile(path string) error {
+	return modelfile.WriteFile(path, m.Save)
+}
+
+// Load reads a model written by Save.
+func Load(r io.Reader) (*Model, error) {
+	var file modelFile
+	if err := modelfile.Read(r, modelMagic, &file); errors.Is(err, modelfile.ErrWrongMagic) {
+		return nil, ErrInvalidModel
+	} else if err != nil {
+		return nil, err
+	}
+	if len(file.Words) != len(file.Forms) {
+		return nil, ErrInvalidModel
+	}
+
+	m := &Model{Forms: make(map[string]string, len(file.Words))}
+	for i, lower := range file.Words {
+		m.Forms[lower] = file.Forms[i]
+	}
+	return m, nil
+}
+
+// LoadFile reads a model from path.
+func LoadFile(path string) (*Model, error) {
+	return modelfile.ReadFile(path, Load)
+}
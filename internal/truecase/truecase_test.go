@@ -0,0 +1,114 @@
+package truecase
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const corpus = `El Real Madrid ganó el partido en el Santiago Bernabéu.
+Ayer el Real Madrid fichó a Mbappé por cinco temporadas.
+Pedro vive en Sevilla y trabaja en Telefónica.
+Hoy vi a Pedro en Sevilla con su hermana.
+Es un acuerdo real y firme. El club ficha a un delantero.
+EL TITULAR EN MAYÚSCULAS NO CUENTA PARA NADA
+`
+
+func trainModel(t *testing.T) *Model {
+	t.Helper()
+
+	m, err := Train(strings.NewReader(corpus))
+	if err != nil {
+		t.Fatalf("Train failed: %v", err)
+	}
+	return m
+}
+
+func TestTrain(t *testing.T) {
+	m := trainModel(t)
+
+	tests := map[string]string{
+		"madrid":  "Madrid",
+		"real":    "Real",
+		"pedro":   "Pedro",
+		"partido": "partido",
+		"ficha":   "ficha",
+	}
+	for lower, expected := range tests {
+		if form := m.Forms[lower]; form != expected {
+			t.Errorf("Expected form %q for %q, but got %q", expected, lower, form)
+		}
+	}
+	if _, ok := m.Forms["titular"]; ok {
+		t.Error("Expected badly cased lines to be skipped")
+	}
+}
+
+func TestBadlyCased(t *testing.T) {
+	tests := map[string]bool{
+		"EL REAL MADRID FICHA A MBAPPÉ":      true,
+		"vi a pedro en sevilla":              true,
+		"Vi a Pedro en Sevilla":              false,
+		"La ONU y la OTAN se reúnen en Roma": false,
+		"OK":                                 false,
+	}
+	for text, expected := range tests {
+		if got := BadlyCased(text); got != expected {
+			t.Errorf("Expected BadlyCased(%q) = %v, but got %v", text, expected, got)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	m := trainModel(t)
+
+	tests := map[string]string{
+		"EL REAL MADRID FICHA A MBAPPÉ": "El Real Madrid ficha a Mbappé",
+		"vi a pedro en sevilla":         "Vi a Pedro en Sevilla",
+		"hola. pedro vive en sevilla":   "Hola. Pedro vive en Sevilla",
+		"Pedro vive en Sevilla":         "Pedro vive en Sevilla",
+		"EL LÍDER ZXQW LLEGA HOY":       "El Líder Zxqw Llega Hoy",
+	}
+	for text, expected := range tests {
+		got, _ := m.Apply(text)
+		if got != expected {
+			t.Errorf("Expected %q for %q, but got %q", expected, text, got)
+		}
+	}
+}
+
+func TestApply_Offsets(t *testing.T) {
+	m := trainModel(t)
+
+	text := "HOY VI A PEDRO EN SEVILLA"
+	cased, offsets := m.Apply(text)
+	start := strings.Index(cased, "Sevilla")
+	s, e := offsets.Span(start, start+len("Sevilla"))
+	if text[s:e] != "SEVILLA" {
+		t.Errorf("Expected offsets to map back to %q, but got %q", "SEVILLA", text[s:e])
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	m := trainModel(t)
+
+	var buf bytes.Buffer
+	if err := m.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Forms) != len(m.Forms) {
+		t.Errorf("Expected %d forms, but got %d", len(m.Forms), len(loaded.Forms))
+	}
+	if loaded.Forms["madrid"] != "Madrid" {
+		t.Errorf("Expected form %q, but got %q", "Madrid", loaded.Forms["madrid"])
+	}
+
+	if _, err := Load(strings.NewReader("not a model")); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("Expected ErrInvalidModel, but got %v", err)
+	}
+}